@@ -7,15 +7,23 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"stox-gateway/internal/aws"
+	"stox-gateway/internal/breaker"
+	"stox-gateway/internal/catalog"
 	"stox-gateway/internal/config"
+	"stox-gateway/internal/features"
 	"stox-gateway/internal/gateway"
 	"stox-gateway/internal/grpcclients"
+	"stox-gateway/internal/health"
+	"stox-gateway/internal/jobs"
+	"stox-gateway/internal/lifecycle"
 	"stox-gateway/internal/logger"
+	"stox-gateway/internal/tlsutil"
 
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 func main() {
@@ -33,16 +41,19 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
 		os.Exit(1)
 	}
-	defer logger.Sync()
 
 	log := logger.Logger
 
+	// Lifecycle manager owns readiness, background tasks and ordered shutdown;
+	// it also syncs the logger once everything has drained
+	lifecycleManager := lifecycle.NewManager(cfg.Server.Shutdown, log)
+
 	// Create auth client
-	authClient, err := grpcclients.NewAuthClient(cfg.Services.Auth.Host, cfg.Services.Auth.Port, log)
+	authClient, err := grpcclients.NewAuthClient(cfg.Services.Auth, log)
 	if err != nil {
 		log.Fatal("Failed to create auth client", zap.Error(err))
 	}
-	defer authClient.Close()
+	lifecycleManager.OnClose("auth-client", authClient.Close)
 
 	log.Info("Auth client created successfully",
 		zap.String("host", cfg.Services.Auth.Host),
@@ -50,15 +61,16 @@ func main() {
 	)
 
 	// Create image client
-	imageClient, err := grpcclients.NewImageClient(cfg.Services.Image.Host, cfg.Services.Image.Port, log)
+	imageClient, err := grpcclients.NewImageClient(cfg.Services.Image, log)
 	if err != nil {
 		log.Fatal("Failed to create image client", zap.Error(err))
 	}
-	defer imageClient.Close()
+	lifecycleManager.OnClose("image-client", imageClient.Close)
 
 	log.Info("Image client created successfully",
 		zap.String("host", cfg.Services.Image.Host),
 		zap.Int("port", cfg.Services.Image.Port),
+		zap.Int("variants", len(cfg.Services.Image.Variants)),
 	)
 
 	// Initialize AWS Services
@@ -66,8 +78,9 @@ func main() {
 
 	// Create S3 service
 	s3Config := aws.S3Config{
-		BucketName: cfg.AWS.S3.BucketName,
-		Region:     cfg.AWS.S3.Region,
+		BucketName:     cfg.AWS.S3.BucketName,
+		Region:         cfg.AWS.S3.Region,
+		CircuitBreaker: breaker.New("s3", cfg.AWS.S3.CircuitBreaker, log),
 	}
 	s3Service, err := aws.NewS3Service(s3Config, log)
 	if err != nil {
@@ -95,16 +108,113 @@ func main() {
 		zap.String("domainName", cloudFrontConfig.DomainName),
 	)
 
+	// Create HTTP-to-gRPC transcoder for config-driven routes
+	transcoder, err := gateway.NewTranscoder(&cfg.Transcoding, &cfg.Services, log)
+	if err != nil {
+		log.Fatal("Failed to create transcoder", zap.Error(err))
+	}
+	lifecycleManager.OnClose("transcoder", transcoder.Close)
+
+	// Create reverse-proxy routes to HTTP upstreams
+	proxyRoutes, err := gateway.NewProxyRoutes(&cfg.Proxy, log)
+	if err != nil {
+		log.Fatal("Failed to create proxy routes", zap.Error(err))
+	}
+
+	// Open the image catalog
+	imageCatalog, err := catalog.Open(cfg.Catalog)
+	if err != nil {
+		log.Fatal("Failed to open image catalog", zap.Error(err))
+	}
+	lifecycleManager.OnClose("catalog", imageCatalog.Close)
+
+	log.Info("Image catalog opened", zap.String("driver", cfg.Catalog.Driver))
+
+	// Create dependency checks for /readyz
+	healthChecker := health.NewChecker(cfg.Health)
+	healthChecker.Register("auth", authClient.CheckHealth)
+	healthChecker.Register("image", imageClient.CheckHealth)
+	if variantChecks := imageClient.VariantChecks(); len(variantChecks) > 1 {
+		// Ready while any variant serves; each variant is reported on its own
+		for name, check := range variantChecks {
+			healthChecker.RegisterOptional("image."+name, check)
+		}
+	}
+	healthChecker.Register("s3", s3Service.CheckBucket)
+	healthChecker.Register("catalog", imageCatalog.Ping)
+	if cfg.Health.CloudFront {
+		healthChecker.Register("cloudfront", func(ctx context.Context) error {
+			_, err := cloudFrontService.GetDistributionConfig(ctx)
+			return err
+		})
+	}
+
+	// Create feature flags
+	flags := features.NewFlags(&cfg.Features)
+
+	// Create the enhancement job pool, resuming jobs left by the last run
+	jobStore, err := jobs.NewFileStore(cfg.Jobs.StoreDir)
+	if err != nil {
+		log.Fatal("Failed to create job store", zap.Error(err))
+	}
+	jobBroker := jobs.NewBroker(cfg.Jobs)
+	jobPool, err := jobs.NewPool(cfg.Jobs, jobStore, jobBroker, log)
+	if err != nil {
+		log.Fatal("Failed to create job pool", zap.Error(err))
+	}
+
 	// Create handlers
 	authHandler := gateway.NewAuthHandler(authClient)
 	imageHandler := gateway.NewImageHandler(imageClient)
-	imageUploadHandler := gateway.NewImageUploadHandler(s3Service, cloudFrontService, imageClient, authClient, log)
+	imageUploadHandler := gateway.NewImageUploadHandler(s3Service, cloudFrontService, imageClient, authClient, flags, lifecycleManager, jobPool, imageCatalog, log)
+	jobPool.OnFinish(imageUploadHandler.EnhancementJobFinished)
+	adminHandler := gateway.NewAdminHandler(flags, imageClient, log)
+	healthHandler := gateway.NewHealthHandler(healthChecker, lifecycleManager.Ready, log)
+	jobEventsHandler := gateway.NewJobEventsHandler(jobBroker, cfg.Jobs.HeartbeatInterval, log)
+
+	// Workers stop taking jobs once the server drains; jobs cut off by the
+	// background timeout resume on the next start
+	lifecycleManager.Go("image-jobs", func(ctx context.Context) {
+		jobPool.Run(ctx, lifecycleManager.Draining(), imageUploadHandler.RunEnhancementJob)
+	})
 
 	// Create router
-	router := gateway.NewRouter(authHandler, imageHandler, imageUploadHandler)
+	router := gateway.NewRouter(authHandler, imageHandler, imageUploadHandler, gateway.RouterOptions{
+		Idempotency:       gateway.IdempotencyMiddleware(&cfg.Idempotency),
+		Flags:             flags,
+		FeatureRetryAfter: cfg.Features.Maintenance.RetryAfter,
+		Admin:             adminHandler,
+		Transcoder:        transcoder,
+		Proxy:             proxyRoutes,
+		Ready:             lifecycleManager.Ready,
+		Health:            healthHandler,
+		JobEvents:         jobEventsHandler,
+	})
+
+	// Create client IP middleware
+	clientIPMiddleware, err := gateway.ClientIPMiddleware(&cfg.ClientIP)
+	if err != nil {
+		log.Fatal("Failed to configure client IP resolution", zap.Error(err))
+	}
+	ipFilterMiddleware, err := gateway.IPFilterMiddleware(&cfg.ClientIP)
+	if err != nil {
+		log.Fatal("Failed to configure IP filter", zap.Error(err))
+	}
 
-	// Apply middleware
-	handler := gateway.CORSMiddleware(&cfg.CORS)(gateway.LoggingMiddleware(router))
+	// Apply middleware, innermost first
+	var handler http.Handler = gateway.ETagMiddleware(router)
+	handler = gateway.MaintenanceMiddleware(flags, &cfg.Features.Maintenance)(handler)
+	handler = ipFilterMiddleware(handler)
+	handler = gateway.LoggingMiddleware(handler)
+	handler = gateway.CORSMiddleware(&cfg.CORS)(handler)
+	handler = gateway.SecurityHeadersMiddleware(&cfg.SecurityHeaders, cfg.Server.Environment)(handler)
+	handler = clientIPMiddleware(handler)
+	handler = gateway.DeadlineMiddleware(&cfg.Deadlines)(handler)
+
+	// Serve HTTP/2 over cleartext when a TLS-terminating proxy sits in front
+	if cfg.Server.H2C && !cfg.Server.TLS.Enabled {
+		handler = h2c.NewHandler(handler, &http2.Server{IdleTimeout: cfg.Server.IdleTimeout})
+	}
 
 	// Create HTTP server
 	server := &http.Server{
@@ -115,13 +225,36 @@ func main() {
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	// Configure TLS termination; certificates are watched for rotation
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+
+	if cfg.Server.TLS.Enabled {
+		tlsConfig, reloaders, err := tlsutil.NewServerConfig(cfg.Server.TLS, log)
+		if err != nil {
+			log.Fatal("Failed to configure TLS", zap.Error(err))
+		}
+		server.TLSConfig = tlsConfig
+		go tlsutil.Watch(watchCtx, cfg.Server.TLS.ReloadInterval, reloaders...)
+	}
+
 	// Start server in a goroutine
 	go func() {
 		log.Info("Starting API Gateway",
 			zap.Int("port", cfg.Server.Port),
 			zap.String("environment", cfg.Server.Environment),
+			zap.Bool("tls", cfg.Server.TLS.Enabled),
+			zap.Bool("h2c", cfg.Server.H2C && !cfg.Server.TLS.Enabled),
 		)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+
+		var err error
+		if cfg.Server.TLS.Enabled {
+			// Certificates come from TLSConfig.GetCertificate
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatal("Failed to start server", zap.Error(err))
 		}
 	}()
@@ -131,15 +264,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Info("Shutting down server...")
-
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown", zap.Error(err))
-	}
-
-	log.Info("Server exited gracefully")
+	// Graceful shutdown: fail readiness, drain, then stop background work and
+	// close clients in reverse order of creation
+	lifecycleManager.Shutdown(server)
 }
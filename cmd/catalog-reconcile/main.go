@@ -0,0 +1,106 @@
+// Command catalog-reconcile repairs drift between the image catalog and the
+// images stored in S3. It finishes deletes a gateway left unfinished, drops
+// catalog keys whose objects are gone and adds objects the catalog doesn't
+// know, such as images uploaded before the catalog existed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"stox-gateway/internal/aws"
+	"stox-gateway/internal/catalog"
+	"stox-gateway/internal/config"
+	"stox-gateway/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report the repairs without making them")
+	prefix := flag.String("prefix", "users/", "only reconcile objects under this S3 prefix, e.g. users/{id}/")
+	deleteGrace := flag.Duration("delete-grace", time.Hour, "finish deletes of images left deleting for longer than this")
+	flag.Parse()
+
+	cfg, err := config.LoadConfig("config.yaml")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := logger.InitLogger(cfg.Logging.Level, cfg.Logging.Format, cfg.Server.Environment); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to initialize logger: %v\n", err)
+		os.Exit(1)
+	}
+	log := logger.Logger
+	defer log.Sync()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	s3Service, err := aws.NewS3Service(aws.S3Config{
+		BucketName: cfg.AWS.S3.BucketName,
+		Region:     cfg.AWS.S3.Region,
+	}, log)
+	if err != nil {
+		log.Fatal("Failed to create S3 service", zap.Error(err))
+	}
+
+	cloudFrontService, err := aws.NewCloudFrontService(aws.CloudFrontConfig{
+		DistributionID: cfg.AWS.CloudFront.DistributionID,
+		DomainName:     cfg.AWS.CloudFront.DomainName,
+		Region:         cfg.AWS.CloudFront.Region,
+	}, log)
+	if err != nil {
+		log.Fatal("Failed to create CloudFront service", zap.Error(err))
+	}
+
+	imageCatalog, err := catalog.Open(cfg.Catalog)
+	if err != nil {
+		log.Fatal("Failed to open image catalog", zap.Error(err))
+	}
+	defer imageCatalog.Close()
+
+	// Deletes younger than the grace period may still be in progress
+	finished, err := imageCatalog.FinishDeletes(ctx, time.Now().Add(-*deleteGrace), s3Service.DeleteImage, *dryRun)
+	if err != nil {
+		log.Fatal("Failed to finish deletes", zap.Error(err))
+	}
+	var deletedKeys []string
+	for _, img := range finished {
+		deletedKeys = append(deletedKeys, img.Keys()...)
+	}
+	if len(deletedKeys) > 0 && !*dryRun {
+		if _, err := cloudFrontService.InvalidateCache(ctx, deletedKeys); err != nil {
+			log.Error("Failed to invalidate CloudFront cache", zap.Error(err))
+		}
+	}
+
+	// Catalog changes made from here on may not show in the listing
+	listedAt := time.Now()
+	objects, err := s3Service.ListImageObjects(ctx, *prefix)
+	if err != nil {
+		log.Fatal("Failed to list images in S3", zap.Error(err))
+	}
+	log.Info("Listed images in S3", zap.String("prefix", *prefix), zap.Int("count", len(objects)))
+
+	report, err := imageCatalog.Reconcile(ctx, objects, listedAt, *prefix, *dryRun)
+	if err != nil {
+		log.Fatal("Failed to reconcile catalog", zap.Error(err))
+	}
+
+	log.Info("Catalog reconciled",
+		zap.Bool("dryRun", *dryRun),
+		zap.Int("deletesFinished", len(finished)),
+		zap.Int("added", report.Added),
+		zap.Int("linked", report.Linked),
+		zap.Int("updated", report.Updated),
+		zap.Int("removed", report.Removed),
+		zap.Int("skipped", report.Skipped),
+	)
+}
@@ -0,0 +1,64 @@
+package aws
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/smithy-go/middleware"
+
+	"stox-gateway/internal/breaker"
+)
+
+// isS3Failure reports whether an S3 error says the service is unhealthy.
+// Client errors such as NoSuchKey or AccessDenied do not count against the
+// circuit.
+func isS3Failure(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var httpErr interface{ HTTPStatusCode() int }
+	if errors.As(err, &httpErr) && httpErr.HTTPStatusCode() < 500 && httpErr.HTTPStatusCode() != 429 {
+		return false
+	}
+	return true
+}
+
+// healthCheckKey marks the context of a health check
+type healthCheckKey struct{}
+
+// withHealthCheck marks ctx as a health check. Health checks skip the circuit
+// breaker: a probe must neither count against the circuit nor take one of its
+// half-open slots, and must not fail readiness just because the circuit is
+// open.
+func withHealthCheck(ctx context.Context) context.Context {
+	return context.WithValue(ctx, healthCheckKey{}, true)
+}
+
+// breakerMiddleware guards every S3 operation with the circuit breaker. It
+// sits in the initialize step, before the SDK's own retries, so an operation
+// and its retries count as one outcome.
+func breakerMiddleware(b *breaker.Breaker) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("CircuitBreaker",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				if ctx.Value(healthCheckKey{}) != nil {
+					return next.HandleInitialize(ctx, in)
+				}
+				done, err := b.Allow()
+				if err != nil {
+					return middleware.InitializeOutput{}, middleware.Metadata{}, err
+				}
+				out, metadata, err := next.HandleInitialize(ctx, in)
+				// The SDK sets no deadline of its own, so a done context
+				// means the caller gave up and says nothing about S3
+				if err != nil && ctx.Err() != nil {
+					done(breaker.Ignored)
+				} else {
+					done(breaker.OutcomeOf(isS3Failure(err)))
+				}
+				return out, metadata, err
+			},
+		), middleware.Before)
+	}
+}
@@ -0,0 +1,47 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// httpError is an error carrying an HTTP status, as SDK response errors do
+type httpError struct {
+	status int
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("http status %d", e.status)
+}
+
+func (e *httpError) HTTPStatusCode() int {
+	return e.status
+}
+
+func TestIsS3Failure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"success", nil, false},
+		{"not found", &httpError{status: 404}, false},
+		{"access denied", &httpError{status: 403}, false},
+		{"wrapped client error", fmt.Errorf("get object: %w", &httpError{status: 400}), false},
+		{"throttled", &httpError{status: 429}, true},
+		{"server error", &httpError{status: 500}, true},
+		{"unavailable", &httpError{status: 503}, true},
+		{"network error", errors.New("connection reset by peer"), true},
+		{"deadline without response", context.DeadlineExceeded, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isS3Failure(tt.err); got != tt.want {
+				t.Errorf("isS3Failure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -16,6 +16,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+
+	"stox-gateway/internal/breaker"
 )
 
 // S3Service handles all S3 operations for the image management system
@@ -32,6 +34,9 @@ type S3Service struct {
 type S3Config struct {
 	BucketName string
 	Region     string
+
+	// CircuitBreaker, if set, fails operations fast while S3 is unhealthy
+	CircuitBreaker *breaker.Breaker
 }
 
 // ImageUploadResult contains the result of an image upload operation
@@ -57,7 +62,11 @@ func NewS3Service(cfg S3Config, logger *zap.Logger) (*S3Service, error) {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
 	}
 
-	client := s3.NewFromConfig(awsCfg)
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.CircuitBreaker != nil {
+			o.APIOptions = append(o.APIOptions, breakerMiddleware(cfg.CircuitBreaker))
+		}
+	})
 	
 	return &S3Service{
 		client:     client,
@@ -230,38 +239,75 @@ func (s *S3Service) DownloadImage(ctx context.Context, key string) ([]byte, erro
 	return buf.Bytes(), nil
 }
 
+// ImageObject describes a stored image as reported by an S3 listing
+type ImageObject struct {
+	Key          string
+	ETag         string
+	Size         int64
+	LastModified time.Time
+}
+
 // ListUserImages lists all images for a specific user
 func (s *S3Service) ListUserImages(ctx context.Context, userID string) ([]string, error) {
-	prefix := fmt.Sprintf("users/%s/", userID)
+	objects, err := s.ListUserImageObjects(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]string, 0, len(objects))
+	for _, obj := range objects {
+		images = append(images, obj.Key)
+	}
+	return images, nil
+}
+
+// ListUserImageObjects lists all images for a specific user together with
+// their S3 ETags, which change whenever an object is rewritten
+func (s *S3Service) ListUserImageObjects(ctx context.Context, userID string) ([]ImageObject, error) {
+	images, err := s.ListImageObjects(ctx, fmt.Sprintf("users/%s/", userID))
+	if err != nil {
+		s.logger.Error("Failed to list user images", 
+			zap.Error(err),
+			zap.String("userID", userID),
+		)
+		return nil, err
+	}
+	
+	s.logger.Info("Listed user images", 
+		zap.String("userID", userID),
+		zap.Int("count", len(images)),
+	)
 	
+	return images, nil
+}
+
+// ListImageObjects lists all objects under prefix, e.g. "users/" for every
+// user's images
+func (s *S3Service) ListImageObjects(ctx context.Context, prefix string) ([]ImageObject, error) {
 	input := &s3.ListObjectsV2Input{
 		Bucket: aws.String(s.bucketName),
 		Prefix: aws.String(prefix),
 	}
 	
-	var images []string
+	var images []ImageObject
 	paginator := s3.NewListObjectsV2Paginator(s.client, input)
 	
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
-			s.logger.Error("Failed to list user images", 
-				zap.Error(err),
-				zap.String("userID", userID),
-			)
-			return nil, fmt.Errorf("failed to list user images: %w", err)
+			return nil, fmt.Errorf("failed to list objects under %s: %w", prefix, err)
 		}
 		
 		for _, obj := range page.Contents {
-			images = append(images, aws.ToString(obj.Key))
+			images = append(images, ImageObject{
+				Key:          aws.ToString(obj.Key),
+				ETag:         aws.ToString(obj.ETag),
+				Size:         aws.ToInt64(obj.Size),
+				LastModified: aws.ToTime(obj.LastModified),
+			})
 		}
 	}
 	
-	s.logger.Info("Listed user images", 
-		zap.String("userID", userID),
-		zap.Int("count", len(images)),
-	)
-	
 	return images, nil
 }
 
@@ -291,3 +337,15 @@ func (s *S3Service) ValidateUserAccess(userID, key string) bool {
 	expectedPrefix := fmt.Sprintf("users/%s/", userID)
 	return strings.HasPrefix(key, expectedPrefix)
 }
+
+// CheckBucket verifies that the bucket exists and is reachable with the
+// current credentials. It bypasses the circuit breaker.
+func (s *S3Service) CheckBucket(ctx context.Context) error {
+	_, err := s.client.HeadBucket(withHealthCheck(ctx), &s3.HeadBucketInput{
+		Bucket: aws.String(s.bucketName),
+	})
+	if err != nil {
+		return fmt.Errorf("bucket %s is not reachable: %w", s.bucketName, err)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"stox-gateway/internal/config"
+)
+
+// ErrOpen matches every OpenError with errors.Is
+var ErrOpen = errors.New("circuit breaker is open")
+
+// OpenError is returned instead of calling a dependency whose circuit is open
+type OpenError struct {
+	Name       string
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open", e.Name)
+}
+
+// Is makes errors.Is(err, ErrOpen) match
+func (e *OpenError) Is(target error) bool {
+	return target == ErrOpen
+}
+
+// State is the state of a circuit
+type State int
+
+const (
+	// Closed lets every call through
+	Closed State = iota
+	// Open rejects every call until the open timeout expires
+	Open
+	// HalfOpen lets a limited number of probe calls through
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Outcome is what a call allowed by the breaker says about the dependency
+type Outcome int
+
+const (
+	// Success counts towards closing the circuit
+	Success Outcome = iota
+	// Failure counts towards opening the circuit
+	Failure
+	// Ignored says nothing about the dependency, such as a call the caller
+	// gave up on. It frees a half-open probe slot without counting.
+	Ignored
+)
+
+// OutcomeOf returns Failure when failed is true and Success otherwise
+func OutcomeOf(failed bool) Outcome {
+	if failed {
+		return Failure
+	}
+	return Success
+}
+
+// Breaker is a consecutive-failure circuit breaker. A nil Breaker lets every
+// call through, so disabled breakers need no special casing by callers.
+type Breaker struct {
+	name   string
+	cfg    config.CircuitBreakerConfig
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+// New creates a breaker for the named dependency, or returns nil when the
+// breaker is disabled
+func New(name string, cfg config.CircuitBreakerConfig, logger *zap.Logger) *Breaker {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = 1
+	}
+	if cfg.HalfOpenRequests < 1 {
+		cfg.HalfOpenRequests = 1
+	}
+	return &Breaker{
+		name:   name,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// Name returns the dependency the breaker guards
+func (b *Breaker) Name() string {
+	if b == nil {
+		return ""
+	}
+	return b.name
+}
+
+// State returns the current state, moving an expired open circuit to half-open
+func (b *Breaker) State() State {
+	if b == nil {
+		return Closed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.expireOpen(time.Now())
+	return b.state
+}
+
+// Allow asks to make a call. It returns an *OpenError when the call must not
+// be made; otherwise the caller must report the outcome through done.
+func (b *Breaker) Allow() (done func(Outcome), err error) {
+	if b == nil {
+		return func(Outcome) {}, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.expireOpen(now)
+
+	switch b.state {
+	case Open:
+		return nil, &OpenError{Name: b.name, RetryAfter: b.openedAt.Add(b.cfg.OpenTimeout).Sub(now)}
+	case HalfOpen:
+		if b.probes >= b.cfg.HalfOpenRequests {
+			return nil, &OpenError{Name: b.name, RetryAfter: b.cfg.OpenTimeout}
+		}
+		b.probes++
+	}
+
+	// Outcomes are attributed to the state the call started in, so that a
+	// slow call from before the circuit opened cannot close it again
+	started := b.openedAt
+	return func(outcome Outcome) { b.record(started, outcome) }, nil
+}
+
+// expireOpen moves an open circuit to half-open once its timeout has passed.
+// Callers hold b.mu.
+func (b *Breaker) expireOpen(now time.Time) {
+	if b.state == Open && now.Sub(b.openedAt) >= b.cfg.OpenTimeout {
+		b.state = HalfOpen
+		b.probes = 0
+		b.successes = 0
+		b.logger.Info("Circuit breaker half-open", zap.String("breaker", b.name))
+	}
+}
+
+// record updates the state with the outcome of a call
+func (b *Breaker) record(started time.Time, outcome Outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !started.Equal(b.openedAt) {
+		// The circuit tripped while this call was in flight
+		return
+	}
+
+	if outcome == Ignored {
+		if b.state == HalfOpen && b.probes > 0 {
+			b.probes--
+		}
+		return
+	}
+
+	switch b.state {
+	case Closed:
+		if outcome == Success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.open(time.Now())
+		}
+
+	case HalfOpen:
+		if outcome == Failure {
+			b.open(time.Now())
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.HalfOpenRequests {
+			b.state = Closed
+			b.failures = 0
+			b.logger.Info("Circuit breaker closed", zap.String("breaker", b.name))
+		}
+	}
+}
+
+// open trips the circuit. Callers hold b.mu.
+func (b *Breaker) open(now time.Time) {
+	b.state = Open
+	b.openedAt = now
+	b.failures = 0
+	b.logger.Warn("Circuit breaker opened",
+		zap.String("breaker", b.name),
+		zap.Duration("openTimeout", b.cfg.OpenTimeout),
+	)
+}
@@ -0,0 +1,175 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"stox-gateway/internal/config"
+)
+
+func newTestBreaker() *Breaker {
+	return New("test", config.CircuitBreakerConfig{
+		Enabled:          true,
+		FailureThreshold: 2,
+		HalfOpenRequests: 2,
+		OpenTimeout:      time.Minute,
+	}, zap.NewNop())
+}
+
+// call makes one call through b with the given outcome
+func call(t *testing.T, b *Breaker, outcome Outcome) {
+	t.Helper()
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() = %v, want nil", err)
+	}
+	done(outcome)
+}
+
+// expire moves b past its open timeout
+func expire(b *Breaker) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.openedAt = b.openedAt.Add(-b.cfg.OpenTimeout)
+}
+
+func TestBreakerStates(t *testing.T) {
+	tests := []struct {
+		name     string
+		outcomes []Outcome
+		// expire moves past the open timeout before the half-open calls
+		expire   bool
+		halfOpen []Outcome
+		want     State
+	}{
+		{
+			name:     "failures below threshold",
+			outcomes: []Outcome{Failure},
+			want:     Closed,
+		},
+		{
+			name:     "success resets failures",
+			outcomes: []Outcome{Failure, Success, Failure},
+			want:     Closed,
+		},
+		{
+			name:     "ignored outcomes neither count nor reset failures",
+			outcomes: []Outcome{Failure, Ignored, Ignored, Failure},
+			want:     Open,
+		},
+		{
+			name:     "opens at threshold",
+			outcomes: []Outcome{Failure, Failure},
+			want:     Open,
+		},
+		{
+			name:     "half-open after timeout",
+			outcomes: []Outcome{Failure, Failure},
+			expire:   true,
+			want:     HalfOpen,
+		},
+		{
+			name:     "closes after enough probes succeed",
+			outcomes: []Outcome{Failure, Failure},
+			expire:   true,
+			halfOpen: []Outcome{Success, Success},
+			want:     Closed,
+		},
+		{
+			name:     "reopens when a probe fails",
+			outcomes: []Outcome{Failure, Failure},
+			expire:   true,
+			halfOpen: []Outcome{Success, Failure},
+			want:     Open,
+		},
+		{
+			name:     "ignored probes free their slot",
+			outcomes: []Outcome{Failure, Failure},
+			expire:   true,
+			halfOpen: []Outcome{Ignored, Ignored, Success, Success},
+			want:     Closed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBreaker()
+			for _, outcome := range tt.outcomes {
+				call(t, b, outcome)
+			}
+			if tt.expire {
+				expire(b)
+			}
+			for _, outcome := range tt.halfOpen {
+				call(t, b, outcome)
+			}
+			if got := b.State(); got != tt.want {
+				t.Errorf("State() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreakerRejectsWhileOpen(t *testing.T) {
+	b := newTestBreaker()
+	call(t, b, Failure)
+	call(t, b, Failure)
+
+	_, err := b.Allow()
+	var openErr *OpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow() = %v, want an OpenError", err)
+	}
+	if openErr.RetryAfter <= 0 || openErr.RetryAfter > time.Minute {
+		t.Errorf("RetryAfter = %s, want within the open timeout", openErr.RetryAfter)
+	}
+}
+
+func TestBreakerLimitsProbes(t *testing.T) {
+	b := newTestBreaker()
+	call(t, b, Failure)
+	call(t, b, Failure)
+	expire(b)
+
+	for i := 0; i < 2; i++ {
+		if _, err := b.Allow(); err != nil {
+			t.Fatalf("probe %d: Allow() = %v, want nil", i+1, err)
+		}
+	}
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Allow() beyond the probe limit = %v, want ErrOpen", err)
+	}
+}
+
+func TestBreakerIgnoresCallsFromBeforeTrip(t *testing.T) {
+	b := newTestBreaker()
+	slow, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	call(t, b, Failure)
+	call(t, b, Failure)
+	expire(b)
+
+	// A success started while closed must not count as a probe
+	slow(Success)
+	call(t, b, Success)
+	if got := b.State(); got != HalfOpen {
+		t.Errorf("State() = %s, want %s", got, HalfOpen)
+	}
+}
+
+func TestNilBreaker(t *testing.T) {
+	var b *Breaker
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("Allow() = %v, want nil", err)
+	}
+	done(Failure)
+	if got := b.State(); got != Closed {
+		t.Errorf("State() = %s, want %s", got, Closed)
+	}
+}
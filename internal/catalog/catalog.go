@@ -0,0 +1,494 @@
+package catalog
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	// Database drivers; both are pure Go
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
+
+	"stox-gateway/internal/config"
+)
+
+// ErrNotFound is returned for unknown images and for images owned by someone
+// else
+var ErrNotFound = errors.New("image not found")
+
+// Status is the processing state of an image
+type Status string
+
+const (
+	StatusUploaded   Status = "uploaded"
+	StatusProcessing Status = "processing"
+	StatusEnhanced   Status = "enhanced"
+	StatusFailed     Status = "failed"
+	// StatusDeleting marks an image whose objects are being deleted. The
+	// row is removed once they are gone.
+	StatusDeleting Status = "deleting"
+)
+
+// Image is a catalog entry linking an uploaded original to its enhanced
+// version and variants. Keys are S3 object keys; empty keys are absent.
+type Image struct {
+	ID          string
+	Owner       string
+	OriginalKey string
+	EnhancedKey string
+	// VariantKeys maps a variant name, e.g. a thumbnail size, to its key
+	VariantKeys map[string]string
+	ContentType string
+	Size        int64
+	Width       int
+	Height      int
+	ProductName string
+	Status      Status
+	// JobID is the enhancement job of the image, if any
+	JobID     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Keys returns every S3 key of the image
+func (img *Image) Keys() []string {
+	var keys []string
+	for _, key := range []string{img.OriginalKey, img.EnhancedKey} {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range img.VariantKeys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Catalog stores images in SQLite or Postgres. Queries use $n placeholders,
+// which both drivers accept.
+type Catalog struct {
+	db *sql.DB
+	// forUpdate locks selected rows in Postgres; SQLite serializes writers
+	// on its own
+	forUpdate string
+}
+
+const columns = `id, owner, original_key, enhanced_key, variant_keys, content_type, size,
+	width, height, product_name, status, job_id, created_at, updated_at`
+
+// Open connects to the catalog database and creates the schema if needed
+func Open(cfg config.CatalogConfig) (*Catalog, error) {
+	var driver, timestamp, forUpdate string
+	dsn := cfg.DSN
+
+	switch cfg.Driver {
+	case "sqlite", "":
+		driver, timestamp = "sqlite", "TIMESTAMP"
+		path := strings.TrimPrefix(strings.SplitN(dsn, "?", 2)[0], "file:")
+		if path != ":memory:" {
+			if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+				return nil, fmt.Errorf("failed to create catalog directory: %w", err)
+			}
+		}
+		if !strings.Contains(dsn, "?") {
+			// Wait for locks instead of failing, let readers run alongside
+			// the writer and store times in a sortable format
+			dsn += "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+		}
+	case "postgres":
+		driver, timestamp, forUpdate = "pgx", "TIMESTAMPTZ", " FOR UPDATE"
+	default:
+		return nil, fmt.Errorf("unknown catalog driver %q", cfg.Driver)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog: %w", err)
+	}
+	if driver == "sqlite" {
+		// SQLite allows a single writer; one connection avoids busy errors
+		db.SetMaxOpenConns(1)
+	} else if cfg.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+
+	schema := []string{
+		`CREATE TABLE IF NOT EXISTS images (
+			id           TEXT PRIMARY KEY,
+			owner        TEXT NOT NULL,
+			original_key TEXT NOT NULL DEFAULT '',
+			enhanced_key TEXT NOT NULL DEFAULT '',
+			variant_keys TEXT NOT NULL DEFAULT '{}',
+			content_type TEXT NOT NULL DEFAULT '',
+			size         BIGINT NOT NULL DEFAULT 0,
+			width        INTEGER NOT NULL DEFAULT 0,
+			height       INTEGER NOT NULL DEFAULT 0,
+			product_name TEXT NOT NULL DEFAULT '',
+			product_name_lower TEXT NOT NULL DEFAULT '',
+			status       TEXT NOT NULL,
+			job_id       TEXT NOT NULL DEFAULT '',
+			created_at   ` + timestamp + ` NOT NULL,
+			updated_at   ` + timestamp + ` NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS images_owner_created ON images (owner, created_at, id)`,
+		`CREATE INDEX IF NOT EXISTS images_original_key ON images (original_key)`,
+		`CREATE INDEX IF NOT EXISTS images_enhanced_key ON images (enhanced_key)`,
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create catalog schema: %w", err)
+		}
+	}
+
+	return &Catalog{db: db, forUpdate: forUpdate}, nil
+}
+
+// Close closes the database
+func (c *Catalog) Close() error {
+	return c.db.Close()
+}
+
+// Ping checks that the database is reachable
+func (c *Catalog) Ping(ctx context.Context) error {
+	return c.db.PingContext(ctx)
+}
+
+// Create adds an image. Timestamps default to now.
+func (c *Catalog) Create(ctx context.Context, img *Image) error {
+	now := time.Now().UTC()
+	if img.CreatedAt.IsZero() {
+		img.CreatedAt = now
+	}
+	if img.UpdatedAt.IsZero() {
+		img.UpdatedAt = now
+	}
+
+	variants, err := encodeVariants(img.VariantKeys)
+	if err != nil {
+		return err
+	}
+	_, err = c.db.ExecContext(ctx, `INSERT INTO images (`+columns+`, product_name_lower)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		img.ID, img.Owner, img.OriginalKey, img.EnhancedKey, variants, img.ContentType, img.Size,
+		img.Width, img.Height, img.ProductName, string(img.Status), img.JobID,
+		img.CreatedAt.UTC(), img.UpdatedAt.UTC(), strings.ToLower(img.ProductName),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create image: %w", err)
+	}
+	return nil
+}
+
+// Get returns the image with the given ID if owner owns it
+func (c *Catalog) Get(ctx context.Context, owner, id string) (*Image, error) {
+	row := c.db.QueryRowContext(ctx, `SELECT `+columns+` FROM images WHERE id = $1 AND owner = $2`, id, owner)
+	return scanImage(row)
+}
+
+// FindByKey returns owner's image that has key as its original, enhanced or
+// variant key
+func (c *Catalog) FindByKey(ctx context.Context, owner, key string) (*Image, error) {
+	row := c.db.QueryRowContext(ctx, `SELECT `+columns+` FROM images
+		WHERE owner = $1 AND (original_key = $2 OR enhanced_key = $2)`, owner, key)
+	img, err := scanImage(row)
+	if !errors.Is(err, ErrNotFound) {
+		return img, err
+	}
+
+	// Variant keys are kept as JSON, so fall back to scanning the owner's
+	// images; variants are rare compared to originals
+	images, err := c.List(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range images {
+		for _, variant := range img.VariantKeys {
+			if variant == key {
+				return img, nil
+			}
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns owner's images, newest first
+func (c *Catalog) List(ctx context.Context, owner string) ([]*Image, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT `+columns+` FROM images
+		WHERE owner = $1 ORDER BY created_at DESC, id DESC`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	return scanImages(rows)
+}
+
+// ListOptions selects a page of an owner's images
+type ListOptions struct {
+	// Type keeps "enhanced" images, which have an enhanced version, or
+	// "original" ones, which have none yet; empty keeps all
+	Type string
+	// Product keeps images whose product name contains it, ignoring case
+	Product string
+	// From and To bound the upload time, From inclusive and To exclusive;
+	// zero times are unbounded
+	From time.Time
+	To   time.Time
+	// Ascending lists the oldest images first instead of the newest
+	Ascending bool
+	Limit     int
+	// Cursor continues from the page that returned it
+	Cursor string
+}
+
+// Page is one page of images. NextCursor is empty on the last page.
+type Page struct {
+	Images     []*Image
+	Total      int
+	NextCursor string
+}
+
+// ErrInvalidCursor is returned for cursors that weren't issued for the
+// requested sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor is the position after the last image of a page
+type cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"id"`
+	Ascending bool      `json:"asc,omitempty"`
+}
+
+func (cur cursor) encode() string {
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	var cur cursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || json.Unmarshal(data, &cur) != nil || cur.ID == "" {
+		return cur, ErrInvalidCursor
+	}
+	return cur, nil
+}
+
+// Page returns a page of owner's images ordered by upload time, with the
+// total number of images matching the filters. Images being deleted are left
+// out of both. Pages are keyset paginated, so images added meanwhile don't
+// shift later pages.
+func (c *Catalog) Page(ctx context.Context, owner string, opts ListOptions) (*Page, error) {
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	where := []string{"owner = " + arg(owner), "status <> " + arg(string(StatusDeleting))}
+	switch opts.Type {
+	case "":
+	case "original":
+		where = append(where, "enhanced_key = ''")
+	case "enhanced":
+		where = append(where, "enhanced_key <> ''")
+	default:
+		return nil, fmt.Errorf("unknown image type %q", opts.Type)
+	}
+	if opts.Product != "" {
+		pattern := "%" + likeEscaper.Replace(strings.ToLower(opts.Product)) + "%"
+		where = append(where, "product_name_lower LIKE "+arg(pattern)+` ESCAPE '\'`)
+	}
+	if !opts.From.IsZero() {
+		where = append(where, "created_at >= "+arg(opts.From.UTC()))
+	}
+	if !opts.To.IsZero() {
+		where = append(where, "created_at < "+arg(opts.To.UTC()))
+	}
+
+	page := &Page{}
+	filter := strings.Join(where, " AND ")
+	if err := c.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM images WHERE `+filter, args...).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("failed to count images: %w", err)
+	}
+
+	direction, compare := "DESC", "<"
+	if opts.Ascending {
+		direction, compare = "ASC", ">"
+	}
+	if opts.Cursor != "" {
+		cur, err := decodeCursor(opts.Cursor)
+		if err != nil || cur.Ascending != opts.Ascending {
+			return nil, ErrInvalidCursor
+		}
+		after := arg(cur.CreatedAt.UTC())
+		where = append(where, "(created_at "+compare+" "+after+" OR (created_at = "+after+" AND id "+compare+" "+arg(cur.ID)+"))")
+	}
+
+	// One extra row tells whether another page follows
+	query := `SELECT ` + columns + ` FROM images WHERE ` + strings.Join(where, " AND ") +
+		` ORDER BY created_at ` + direction + `, id ` + direction + ` LIMIT ` + arg(opts.Limit+1)
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	images, err := scanImages(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(images) > opts.Limit {
+		images = images[:opts.Limit]
+		last := images[len(images)-1]
+		page.NextCursor = cursor{CreatedAt: last.CreatedAt, ID: last.ID, Ascending: opts.Ascending}.encode()
+	}
+	page.Images = images
+	return page, nil
+}
+
+// likeEscaper escapes LIKE wildcards in a search term
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// All returns every image in the catalog
+func (c *Catalog) All(ctx context.Context) ([]*Image, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT `+columns+` FROM images ORDER BY owner, created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	return scanImages(rows)
+}
+
+// Update changes owner's image in a transaction. If change returns an error
+// the transaction is rolled back and the error returned. change must not do
+// slow I/O such as S3 calls: the row stays locked, and with SQLite the whole
+// catalog waits, until it returns.
+func (c *Catalog) Update(ctx context.Context, owner, id string, change func(img *Image) error) (*Image, error) {
+	var updated *Image
+	err := c.inTx(ctx, func(tx *sql.Tx) error {
+		img, err := c.lock(ctx, tx, owner, id)
+		if err != nil {
+			return err
+		}
+		if err := change(img); err != nil {
+			return err
+		}
+
+		variants, err := encodeVariants(img.VariantKeys)
+		if err != nil {
+			return err
+		}
+		img.UpdatedAt = time.Now().UTC()
+		_, err = tx.ExecContext(ctx, `UPDATE images SET original_key = $1, enhanced_key = $2,
+			variant_keys = $3, content_type = $4, size = $5, width = $6, height = $7,
+			product_name = $8, product_name_lower = $9, status = $10, job_id = $11, updated_at = $12
+			WHERE id = $13`,
+			img.OriginalKey, img.EnhancedKey, variants, img.ContentType, img.Size, img.Width, img.Height,
+			img.ProductName, strings.ToLower(img.ProductName), string(img.Status), img.JobID, img.UpdatedAt, img.ID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update image: %w", err)
+		}
+		updated = img
+		return nil
+	})
+	return updated, err
+}
+
+// Delete removes owner's image in a transaction. check is called with the
+// image before the row is deleted; if it fails the image is kept. A nil
+// check always deletes the row. Like Update's change, check must be quick.
+func (c *Catalog) Delete(ctx context.Context, owner, id string, check func(img *Image) error) error {
+	return c.inTx(ctx, func(tx *sql.Tx) error {
+		img, err := c.lock(ctx, tx, owner, id)
+		if err != nil {
+			return err
+		}
+		if check != nil {
+			if err := check(img); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM images WHERE id = $1`, img.ID); err != nil {
+			return fmt.Errorf("failed to delete image: %w", err)
+		}
+		return nil
+	})
+}
+
+// lock reads an image for update within tx
+func (c *Catalog) lock(ctx context.Context, tx *sql.Tx, owner, id string) (*Image, error) {
+	row := tx.QueryRowContext(ctx, `SELECT `+columns+` FROM images WHERE id = $1 AND owner = $2`+c.forUpdate, id, owner)
+	return scanImage(row)
+}
+
+// inTx runs fn in a transaction, committing if it succeeds
+func (c *Catalog) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanImage(row scanner) (*Image, error) {
+	img := &Image{}
+	var variants, status string
+	err := row.Scan(&img.ID, &img.Owner, &img.OriginalKey, &img.EnhancedKey, &variants, &img.ContentType,
+		&img.Size, &img.Width, &img.Height, &img.ProductName, &status, &img.JobID, &img.CreatedAt, &img.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	img.Status = Status(status)
+	if err := json.Unmarshal([]byte(variants), &img.VariantKeys); err != nil {
+		return nil, fmt.Errorf("failed to read variant keys of image %s: %w", img.ID, err)
+	}
+	return img, nil
+}
+
+func scanImages(rows *sql.Rows) ([]*Image, error) {
+	defer rows.Close()
+
+	var images []*Image
+	for rows.Next() {
+		img, err := scanImage(rows)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	return images, nil
+}
+
+func encodeVariants(variants map[string]string) (string, error) {
+	if len(variants) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(variants)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,287 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"stox-gateway/internal/config"
+)
+
+var base = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// openTestCatalog returns an in-memory catalog holding alice's images a to f,
+// uploaded an hour apart in that order, and one image of bob's
+func openTestCatalog(t *testing.T) *Catalog {
+	t.Helper()
+	c, err := Open(config.CatalogConfig{Driver: "sqlite", DSN: ":memory:"})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	images := []*Image{
+		{ID: "a", Owner: "alice", OriginalKey: "o/a", ProductName: "Red Shoe", Status: StatusUploaded},
+		{ID: "b", Owner: "alice", OriginalKey: "o/b", EnhancedKey: "e/b", ProductName: "Blue Shoe", Status: StatusEnhanced},
+		{ID: "c", Owner: "alice", OriginalKey: "o/c", ProductName: "ÉCHARPE", Status: StatusProcessing},
+		{ID: "d", Owner: "alice", OriginalKey: "o/d", EnhancedKey: "e/d", ProductName: "100%_cotton", Status: StatusEnhanced},
+		{ID: "e", Owner: "alice", OriginalKey: "o/e", EnhancedKey: "e/e", ProductName: "Red Hat", Status: StatusDeleting},
+		{ID: "f", Owner: "alice", OriginalKey: "o/f", Status: StatusFailed},
+		{ID: "g", Owner: "bob", OriginalKey: "o/g", ProductName: "Red Shoe", Status: StatusUploaded},
+	}
+	for i, img := range images {
+		img.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if err := c.Create(context.Background(), img); err != nil {
+			t.Fatalf("Create %s: %v", img.ID, err)
+		}
+	}
+	return c
+}
+
+func pageIDs(page *Page) []string {
+	ids := []string{}
+	for _, img := range page.Images {
+		ids = append(ids, img.ID)
+	}
+	return ids
+}
+
+func TestPageFilters(t *testing.T) {
+	c := openTestCatalog(t)
+
+	tests := []struct {
+		name      string
+		opts      ListOptions
+		wantIDs   []string
+		wantTotal int
+	}{
+		{
+			name:      "newest first without deleting images",
+			opts:      ListOptions{},
+			wantIDs:   []string{"f", "d", "c", "b", "a"},
+			wantTotal: 5,
+		},
+		{
+			name:      "oldest first",
+			opts:      ListOptions{Ascending: true},
+			wantIDs:   []string{"a", "b", "c", "d", "f"},
+			wantTotal: 5,
+		},
+		{
+			name:      "enhanced",
+			opts:      ListOptions{Type: "enhanced"},
+			wantIDs:   []string{"d", "b"},
+			wantTotal: 2,
+		},
+		{
+			name:      "original",
+			opts:      ListOptions{Type: "original"},
+			wantIDs:   []string{"f", "c", "a"},
+			wantTotal: 3,
+		},
+		{
+			name:      "product ignores case",
+			opts:      ListOptions{Product: "SHOE"},
+			wantIDs:   []string{"b", "a"},
+			wantTotal: 2,
+		},
+		{
+			name:      "product ignores case beyond ASCII",
+			opts:      ListOptions{Product: "écharpe"},
+			wantIDs:   []string{"c"},
+			wantTotal: 1,
+		},
+		{
+			name:      "product wildcards match literally",
+			opts:      ListOptions{Product: "%_"},
+			wantIDs:   []string{"d"},
+			wantTotal: 1,
+		},
+		{
+			name:      "from inclusive, to exclusive",
+			opts:      ListOptions{From: base.Add(time.Hour), To: base.Add(3 * time.Hour)},
+			wantIDs:   []string{"c", "b"},
+			wantTotal: 2,
+		},
+		{
+			name:      "filters combine",
+			opts:      ListOptions{Type: "original", Product: "red", From: base},
+			wantIDs:   []string{"a"},
+			wantTotal: 1,
+		},
+		{
+			name:      "total counts beyond the limit",
+			opts:      ListOptions{Limit: 2},
+			wantIDs:   []string{"f", "d"},
+			wantTotal: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.opts.Limit == 0 {
+				tt.opts.Limit = 10
+			}
+			page, err := c.Page(context.Background(), "alice", tt.opts)
+			if err != nil {
+				t.Fatalf("Page: %v", err)
+			}
+			if got := pageIDs(page); !reflect.DeepEqual(got, tt.wantIDs) {
+				t.Errorf("images = %v, want %v", got, tt.wantIDs)
+			}
+			if page.Total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", page.Total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestPageCursor(t *testing.T) {
+	c := openTestCatalog(t)
+
+	tests := []struct {
+		name      string
+		opts      ListOptions
+		wantPages [][]string
+	}{
+		{
+			name:      "newest first",
+			opts:      ListOptions{Limit: 2},
+			wantPages: [][]string{{"f", "d"}, {"c", "b"}, {"a"}},
+		},
+		{
+			name:      "oldest first",
+			opts:      ListOptions{Limit: 2, Ascending: true},
+			wantPages: [][]string{{"a", "b"}, {"c", "d"}, {"f"}},
+		},
+		{
+			name:      "exact multiple of the limit",
+			opts:      ListOptions{Limit: 5},
+			wantPages: [][]string{{"f", "d", "c", "b", "a"}},
+		},
+		{
+			name:      "filtered",
+			opts:      ListOptions{Limit: 1, Type: "enhanced"},
+			wantPages: [][]string{{"d"}, {"b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			var pages [][]string
+			for {
+				page, err := c.Page(context.Background(), "alice", opts)
+				if err != nil {
+					t.Fatalf("Page %d: %v", len(pages)+1, err)
+				}
+				pages = append(pages, pageIDs(page))
+				if page.NextCursor == "" || len(pages) > len(tt.wantPages) {
+					break
+				}
+				opts.Cursor = page.NextCursor
+			}
+			if !reflect.DeepEqual(pages, tt.wantPages) {
+				t.Errorf("pages = %v, want %v", pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestPageInvalidCursor(t *testing.T) {
+	c := openTestCatalog(t)
+
+	first, err := c.Page(context.Background(), "alice", ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("Page: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+	}{
+		{name: "not base64", opts: ListOptions{Limit: 2, Cursor: "***"}},
+		{name: "not JSON", opts: ListOptions{Limit: 2, Cursor: "bm90IGpzb24"}},
+		{name: "other sort order", opts: ListOptions{Limit: 2, Ascending: true, Cursor: first.NextCursor}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.Page(context.Background(), "alice", tt.opts); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("err = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func TestFinishDeletes(t *testing.T) {
+	c := openTestCatalog(t)
+	ctx := context.Background()
+	updated := func(id string) time.Time {
+		img, err := c.Get(ctx, "alice", id)
+		if err != nil {
+			t.Fatalf("Get %s: %v", id, err)
+		}
+		return img.UpdatedAt
+	}
+
+	tests := []struct {
+		name   string
+		cutoff time.Time
+		dryRun bool
+		// wantIDs are the images finished; wantKeys the objects deleted
+		wantIDs  []string
+		wantKeys []string
+	}{
+		{
+			name:   "within the grace period",
+			cutoff: updated("e"),
+		},
+		{
+			name:    "dry run",
+			cutoff:  updated("e").Add(time.Second),
+			dryRun:  true,
+			wantIDs: []string{"e"},
+		},
+		{
+			name:     "stuck delete",
+			cutoff:   updated("e").Add(time.Second),
+			wantIDs:  []string{"e"},
+			wantKeys: []string{"o/e", "e/e"},
+		},
+		{
+			name:   "already finished",
+			cutoff: updated("f").Add(time.Second),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var keys []string
+			deleteObject := func(_ context.Context, key string) error {
+				keys = append(keys, key)
+				return nil
+			}
+			finished, err := c.FinishDeletes(ctx, tt.cutoff, deleteObject, tt.dryRun)
+			if err != nil {
+				t.Fatalf("FinishDeletes: %v", err)
+			}
+			var ids []string
+			for _, img := range finished {
+				ids = append(ids, img.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("finished = %v, want %v", ids, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(keys, tt.wantKeys) {
+				t.Errorf("deleted = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+
+	if _, err := c.Get(ctx, "alice", "e"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get e: err = %v, want ErrNotFound", err)
+	}
+}
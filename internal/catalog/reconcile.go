@@ -0,0 +1,303 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"stox-gateway/internal/aws"
+)
+
+// ReconcileReport counts the repairs made by Reconcile
+type ReconcileReport struct {
+	// Added counts images created for objects missing from the catalog
+	Added int `json:"added"`
+	// Linked counts enhanced objects attached to their catalogued original
+	Linked int `json:"linked"`
+	// Updated counts images whose missing keys or sizes were corrected
+	Updated int `json:"updated"`
+	// Removed counts images none of whose objects exist any more
+	Removed int `json:"removed"`
+	// Skipped counts objects outside the users/{id}/{type}/ layout
+	Skipped int `json:"skipped"`
+}
+
+// errUnchanged rolls back an update that turned out to change nothing
+var errUnchanged = errors.New("unchanged")
+
+// Reconcile repairs drift between the catalog and the objects in S3: keys of
+// deleted objects are dropped, images without any objects are removed, and
+// objects the catalog doesn't know are added, with enhanced objects linked to
+// their original where its name allows. objects are all objects under
+// prefix, listed from listedAt on; keys outside it are left alone. Images
+// changed since listedAt are left alone too, as the listing may predate
+// their objects. With dryRun nothing is written.
+func (c *Catalog) Reconcile(ctx context.Context, objects []aws.ImageObject, listedAt time.Time, prefix string, dryRun bool) (ReconcileReport, error) {
+	var report ReconcileReport
+
+	existing := listing{prefix: prefix, objects: make(map[string]aws.ImageObject, len(objects))}
+	for _, obj := range objects {
+		existing.objects[obj.Key] = obj
+	}
+
+	images, err := c.All(ctx)
+	if err != nil {
+		return report, err
+	}
+
+	referenced := make(map[string]bool)
+	byOriginal := make(map[string]*Image)
+	for _, img := range images {
+		repaired := *img
+		if img.UpdatedAt.After(listedAt) || !repair(&repaired, existing) {
+			track(img, referenced, byOriginal)
+			continue
+		}
+
+		if len(repaired.Keys()) == 0 {
+			report.Removed++
+			if !dryRun {
+				err := c.Delete(ctx, img.Owner, img.ID, func(current *Image) error {
+					if current.UpdatedAt.After(listedAt) {
+						return errUnchanged
+					}
+					return nil
+				})
+				if err != nil && !errors.Is(err, errUnchanged) && !errors.Is(err, ErrNotFound) {
+					return report, err
+				}
+			}
+			continue
+		}
+
+		report.Updated++
+		if !dryRun {
+			// Repair the row as it is now, in case it changed meanwhile
+			_, err := c.Update(ctx, img.Owner, img.ID, func(current *Image) error {
+				if current.UpdatedAt.After(listedAt) || !repair(current, existing) {
+					return errUnchanged
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errUnchanged) && !errors.Is(err, ErrNotFound) {
+				return report, err
+			}
+		}
+		track(&repaired, referenced, byOriginal)
+	}
+
+	// Originals first, so that enhanced objects can be linked to originals
+	// added in the same run
+	var enhanced []aws.ImageObject
+	for _, obj := range objects {
+		if referenced[obj.Key] {
+			continue
+		}
+		owner, kind, _, ok := parseKey(obj.Key)
+		switch {
+		case !ok:
+			report.Skipped++
+		case kind == "enhanced":
+			enhanced = append(enhanced, obj)
+		case kind != "original":
+			report.Skipped++
+		default:
+			// Catalogued since the catalog was read, e.g. by an upload in
+			// progress
+			if catalogued, err := c.catalogued(ctx, owner, obj.Key); catalogued || err != nil {
+				if err != nil {
+					return report, err
+				}
+				continue
+			}
+			img := &Image{
+				ID:          uuid.New().String(),
+				Owner:       owner,
+				OriginalKey: obj.Key,
+				ContentType: mime.TypeByExtension(path.Ext(obj.Key)),
+				Size:        obj.Size,
+				Status:      StatusUploaded,
+				CreatedAt:   obj.LastModified,
+			}
+			if !dryRun {
+				if err := c.Create(ctx, img); err != nil {
+					return report, err
+				}
+			}
+			report.Added++
+			byOriginal[img.OriginalKey] = img
+		}
+	}
+
+	for _, obj := range enhanced {
+		owner, _, file, _ := parseKey(obj.Key)
+		if original, ok := byOriginal[originalKeyOf(owner, file)]; ok && original.EnhancedKey == "" {
+			if !dryRun {
+				_, err := c.Update(ctx, owner, original.ID, func(img *Image) error {
+					if img.EnhancedKey != "" {
+						return errUnchanged
+					}
+					img.EnhancedKey = obj.Key
+					img.Status = StatusEnhanced
+					return nil
+				})
+				if err != nil && !errors.Is(err, errUnchanged) {
+					return report, err
+				}
+			}
+			original.EnhancedKey = obj.Key
+			report.Linked++
+			continue
+		}
+
+		if catalogued, err := c.catalogued(ctx, owner, obj.Key); catalogued || err != nil {
+			if err != nil {
+				return report, err
+			}
+			continue
+		}
+		img := &Image{
+			ID:          uuid.New().String(),
+			Owner:       owner,
+			EnhancedKey: obj.Key,
+			ContentType: mime.TypeByExtension(path.Ext(obj.Key)),
+			Size:        obj.Size,
+			Status:      StatusEnhanced,
+			CreatedAt:   obj.LastModified,
+		}
+		if !dryRun {
+			if err := c.Create(ctx, img); err != nil {
+				return report, err
+			}
+		}
+		report.Added++
+	}
+
+	return report, nil
+}
+
+// FinishDeletes finishes deleting images left marked as deleting since before
+// cutoff, e.g. by a gateway that failed or stopped mid-delete. deleteObject
+// must treat missing objects as deleted. It returns the finished images, or
+// with dryRun the images it would finish.
+func (c *Catalog) FinishDeletes(ctx context.Context, cutoff time.Time, deleteObject func(ctx context.Context, key string) error, dryRun bool) ([]*Image, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT `+columns+` FROM images
+		WHERE status = $1 AND updated_at < $2 ORDER BY owner, created_at, id`, string(StatusDeleting), cutoff.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deleting images: %w", err)
+	}
+	stuck, err := scanImages(rows)
+	if err != nil || dryRun {
+		return stuck, err
+	}
+
+	var finished []*Image
+	for _, img := range stuck {
+		for _, key := range img.Keys() {
+			if err := deleteObject(ctx, key); err != nil {
+				return finished, err
+			}
+		}
+		// A retried request may have deleted the row meanwhile
+		err := c.Delete(ctx, img.Owner, img.ID, nil)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return finished, err
+		}
+		finished = append(finished, img)
+	}
+	return finished, nil
+}
+
+// catalogued reports whether owner has an image with key
+func (c *Catalog) catalogued(ctx context.Context, owner, key string) (bool, error) {
+	_, err := c.FindByKey(ctx, owner, key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// track records the keys of a reconciled image
+func track(img *Image, referenced map[string]bool, byOriginal map[string]*Image) {
+	for _, key := range img.Keys() {
+		referenced[key] = true
+	}
+	if img.OriginalKey != "" {
+		byOriginal[img.OriginalKey] = img
+	}
+}
+
+// listing holds the objects found under prefix
+type listing struct {
+	prefix  string
+	objects map[string]aws.ImageObject
+}
+
+// missing reports whether key was listed and not found
+func (l listing) missing(key string) bool {
+	_, ok := l.objects[key]
+	return !ok && strings.HasPrefix(key, l.prefix)
+}
+
+// repair drops the keys of objects that no longer exist and corrects the size
+// of the original, reporting whether anything changed
+func repair(img *Image, existing listing) bool {
+	changed := false
+
+	if img.OriginalKey != "" {
+		if existing.missing(img.OriginalKey) {
+			img.OriginalKey = ""
+			changed = true
+		} else if obj, ok := existing.objects[img.OriginalKey]; ok && obj.Size != img.Size {
+			img.Size = obj.Size
+			changed = true
+		}
+	}
+	if img.EnhancedKey != "" {
+		if existing.missing(img.EnhancedKey) {
+			img.EnhancedKey = ""
+			if img.Status == StatusEnhanced {
+				img.Status = StatusUploaded
+			}
+			changed = true
+		}
+	}
+	if len(img.VariantKeys) > 0 {
+		variants := make(map[string]string, len(img.VariantKeys))
+		for name, key := range img.VariantKeys {
+			if existing.missing(key) {
+				changed = true
+			} else {
+				variants[name] = key
+			}
+		}
+		img.VariantKeys = variants
+	}
+	return changed
+}
+
+// parseKey splits a key of the form users/{owner}/{kind}/{file}
+func parseKey(key string) (owner, kind, file string, ok bool) {
+	parts := strings.SplitN(key, "/", 4)
+	if len(parts) != 4 || parts[0] != "users" || parts[1] == "" || parts[2] == "" || parts[3] == "" {
+		return "", "", "", false
+	}
+	return parts[1], parts[2], parts[3], true
+}
+
+// originalKeyOf guesses the original of an enhanced file. The gateway names
+// enhanced files "enhanced_{original name}_enhanced_{uuid}{ext}".
+func originalKeyOf(owner, file string) string {
+	ext := path.Ext(file)
+	name := strings.TrimPrefix(strings.TrimSuffix(file, ext), "enhanced_")
+	if i := strings.LastIndex(name, "_enhanced_"); i >= 0 {
+		name = name[:i]
+	}
+	return "users/" + owner + "/original/" + name + ext
+}
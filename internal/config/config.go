@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -9,12 +10,22 @@ import (
 
 // Config holds all configuration for the API Gateway
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Services ServicesConfig `mapstructure:"services"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
-	CORS     CORSConfig     `mapstructure:"cors"`
-	AWS      AWSConfig      `mapstructure:"aws"`
+	Server          ServerConfig          `mapstructure:"server"`
+	Services        ServicesConfig        `mapstructure:"services"`
+	JWT             JWTConfig             `mapstructure:"jwt"`
+	Logging         LoggingConfig         `mapstructure:"logging"`
+	CORS            CORSConfig            `mapstructure:"cors"`
+	AWS             AWSConfig             `mapstructure:"aws"`
+	SecurityHeaders SecurityHeadersConfig `mapstructure:"security_headers"`
+	Idempotency     IdempotencyConfig     `mapstructure:"idempotency"`
+	Deadlines       DeadlinesConfig       `mapstructure:"deadlines"`
+	ClientIP        ClientIPConfig        `mapstructure:"client_ip"`
+	Features        FeaturesConfig        `mapstructure:"features"`
+	Transcoding     TranscodingConfig     `mapstructure:"transcoding"`
+	Proxy           ProxyConfig           `mapstructure:"proxy"`
+	Health          HealthConfig          `mapstructure:"health"`
+	Jobs            JobsConfig            `mapstructure:"jobs"`
+	Catalog         CatalogConfig         `mapstructure:"catalog"`
 }
 
 // ServerConfig holds HTTP server configuration
@@ -24,6 +35,38 @@ type ServerConfig struct {
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`
 	Environment  string        `mapstructure:"environment"`
+
+	// TLS terminates HTTPS in the gateway itself
+	TLS ServerTLSConfig `mapstructure:"tls"`
+	// H2C serves HTTP/2 over cleartext, for deployments behind a proxy that
+	// terminates TLS and speaks HTTP/2 to the gateway. Ignored when TLS is on.
+	H2C bool `mapstructure:"h2c"`
+
+	// Shutdown controls how the gateway drains on SIGTERM
+	Shutdown ShutdownConfig `mapstructure:"shutdown"`
+}
+
+// ShutdownConfig holds graceful shutdown settings. On SIGTERM readiness fails
+// first, then after DrainDelay the server stops accepting connections and
+// waits up to Timeout for in-flight requests. Background tasks then get up to
+// BackgroundTimeout to finish before clients are closed.
+type ShutdownConfig struct {
+	DrainDelay        time.Duration `mapstructure:"drain_delay"`
+	Timeout           time.Duration `mapstructure:"timeout"`
+	BackgroundTimeout time.Duration `mapstructure:"background_timeout"`
+}
+
+// ServerTLSConfig holds TLS termination settings. Certificate, key and client
+// CA files are re-read when they change on disk.
+type ServerTLSConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	CertFile       string        `mapstructure:"cert_file"`
+	KeyFile        string        `mapstructure:"key_file"`
+	MinVersion     string        `mapstructure:"min_version"`
+	CipherSuites   []string      `mapstructure:"cipher_suites"`
+	ClientAuth     string        `mapstructure:"client_auth"`
+	ClientCAFile   string        `mapstructure:"client_ca_file"`
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
 }
 
 // ServicesConfig holds microservice endpoints
@@ -35,10 +78,187 @@ type ServicesConfig struct {
 	Agent ServiceConfig `mapstructure:"agent"`
 }
 
+// ByName returns the service config for a name used in the services section
+func (s *ServicesConfig) ByName(name string) (ServiceConfig, bool) {
+	switch name {
+	case "auth":
+		return s.Auth, true
+	case "image":
+		return s.Image, true
+	case "llm":
+		return s.LLM, true
+	case "queue":
+		return s.Queue, true
+	case "agent":
+		return s.Agent, true
+	}
+	return ServiceConfig{}, false
+}
+
 // ServiceConfig holds individual service configuration
 type ServiceConfig struct {
 	Host string `mapstructure:"host"`
 	Port int    `mapstructure:"port"`
+
+	// Variants splits traffic across several weighted backends, e.g. a
+	// stable and a canary image-service. When empty, Host and Port are the
+	// only backend.
+	Variants []ServiceVariant `mapstructure:"variants"`
+	Routing  VariantRouting   `mapstructure:"routing"`
+
+	// Shadow mirrors a sample of requests to a candidate backend
+	Shadow ShadowConfig `mapstructure:"shadow"`
+
+	// Streaming sends large payloads in chunks instead of one message
+	Streaming StreamingConfig `mapstructure:"streaming"`
+
+	// TLS secures the connection to every backend of the service, including
+	// variants and the shadow
+	TLS ClientTLSConfig `mapstructure:"tls"`
+
+	// RetryPolicies retries or hedges individual methods. Methods without a
+	// policy are attempted once.
+	RetryPolicies []RetryPolicy `mapstructure:"retry_policies"`
+
+	// CircuitBreaker fails calls fast while the service is unhealthy
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+
+	// Discovery finds the service's endpoints and LoadBalancing spreads
+	// calls across them
+	Discovery     DiscoveryConfig     `mapstructure:"discovery"`
+	LoadBalancing LoadBalancingConfig `mapstructure:"load_balancing"`
+
+	// Timeout bounds every call, including retries; MethodTimeouts overrides
+	// it for individual methods. Neither extends the caller's deadline.
+	Timeout        time.Duration   `mapstructure:"timeout"`
+	MethodTimeouts []MethodTimeout `mapstructure:"method_timeouts"`
+
+	// Transport settings. Zero message sizes keep gRPC's 4MB defaults.
+	MaxSendMessageSize int             `mapstructure:"max_send_message_size"`
+	MaxRecvMessageSize int             `mapstructure:"max_recv_message_size"`
+	Keepalive          KeepaliveConfig `mapstructure:"keepalive"`
+	// Compression is "" for none or "gzip"
+	Compression string `mapstructure:"compression"`
+}
+
+// MethodTimeout overrides the service timeout for one method, e.g.
+// "ValidateToken"
+type MethodTimeout struct {
+	Method  string        `mapstructure:"method"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// KeepaliveConfig holds gRPC client keepalive settings. A zero Time disables
+// keepalive pings; backends must permit the chosen ping interval.
+type KeepaliveConfig struct {
+	Time                time.Duration `mapstructure:"time"`
+	Timeout             time.Duration `mapstructure:"timeout"`
+	PermitWithoutStream bool          `mapstructure:"permit_without_stream"`
+}
+
+// DiscoveryConfig selects how endpoints are found. Mode is "" for Host and
+// Port as given, "dns" to resolve every A/AAAA record of Host, "static" for
+// the Endpoints list, or "file" for a file listing one host:port per line
+// that is re-read every RefreshInterval. Static and file discovery cannot be
+// combined with variants.
+type DiscoveryConfig struct {
+	Mode            string        `mapstructure:"mode"`
+	Endpoints       []string      `mapstructure:"endpoints"`
+	File            string        `mapstructure:"file"`
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+}
+
+// LoadBalancingConfig selects the balancing policy: "pick_first" (one
+// connection, the default), "round_robin" or "least_request"
+type LoadBalancingConfig struct {
+	Policy           string                 `mapstructure:"policy"`
+	OutlierDetection OutlierDetectionConfig `mapstructure:"outlier_detection"`
+}
+
+// OutlierDetectionConfig ejects an endpoint from balancing after
+// ConsecutiveFailures failed calls, for BaseEjectionTime times the number of
+// times it has been ejected. At most MaxEjectionPercent of the endpoints are
+// ejected at once.
+type OutlierDetectionConfig struct {
+	Enabled             bool          `mapstructure:"enabled"`
+	ConsecutiveFailures int           `mapstructure:"consecutive_failures"`
+	BaseEjectionTime    time.Duration `mapstructure:"base_ejection_time"`
+	MaxEjectionPercent  int           `mapstructure:"max_ejection_percent"`
+}
+
+// CircuitBreakerConfig holds circuit breaker settings for one dependency. The
+// circuit opens after FailureThreshold consecutive failures and rejects calls
+// for OpenTimeout. It then lets HalfOpenRequests probe calls through; if they
+// all succeed the circuit closes, and any failure opens it again.
+type CircuitBreakerConfig struct {
+	Enabled          bool          `mapstructure:"enabled"`
+	FailureThreshold int           `mapstructure:"failure_threshold"`
+	OpenTimeout      time.Duration `mapstructure:"open_timeout"`
+	HalfOpenRequests int           `mapstructure:"half_open_requests"`
+}
+
+// RetryPolicy retries the listed methods on retryable status codes with
+// jittered exponential backoff. With HedgingDelay set, further attempts are
+// started after that delay without waiting for earlier ones, and the first
+// success wins; only use hedging for idempotent methods. Attempts never
+// outlive the caller's deadline.
+type RetryPolicy struct {
+	Methods           []string      `mapstructure:"methods"`
+	MaxAttempts       int           `mapstructure:"max_attempts"`
+	InitialBackoff    time.Duration `mapstructure:"initial_backoff"`
+	MaxBackoff        time.Duration `mapstructure:"max_backoff"`
+	BackoffMultiplier float64       `mapstructure:"backoff_multiplier"`
+	RetryableCodes    []string      `mapstructure:"retryable_codes"`
+	HedgingDelay      time.Duration `mapstructure:"hedging_delay"`
+}
+
+// ClientTLSConfig holds TLS settings for a gRPC client. An empty CAFile uses
+// the system roots; CertFile and KeyFile enable mTLS. Files are re-read when
+// they change on disk.
+type ClientTLSConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	CAFile         string        `mapstructure:"ca_file"`
+	CertFile       string        `mapstructure:"cert_file"`
+	KeyFile        string        `mapstructure:"key_file"`
+	ServerName     string        `mapstructure:"server_name"`
+	MinVersion     string        `mapstructure:"min_version"`
+	ReloadInterval time.Duration `mapstructure:"reload_interval"`
+}
+
+// ShadowConfig holds shadow traffic settings. Shadow calls run asynchronously
+// with their own timeout and their responses are discarded after comparison.
+type ShadowConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	Host           string        `mapstructure:"host"`
+	Port           int           `mapstructure:"port"`
+	SampleRate     float64       `mapstructure:"sample_rate"`
+	MaxConcurrency int           `mapstructure:"max_concurrency"`
+	Timeout        time.Duration `mapstructure:"timeout"`
+}
+
+// StreamingConfig holds chunked transfer settings. Payloads of at least
+// Threshold bytes are sent in ChunkSize pieces; backends without the
+// streaming RPC get the unary call instead.
+type StreamingConfig struct {
+	Enabled   bool `mapstructure:"enabled"`
+	Threshold int  `mapstructure:"threshold"`
+	ChunkSize int  `mapstructure:"chunk_size"`
+}
+
+// ServiceVariant is one weighted backend of a service
+type ServiceVariant struct {
+	Name   string `mapstructure:"name"`
+	Host   string `mapstructure:"host"`
+	Port   int    `mapstructure:"port"`
+	Weight int    `mapstructure:"weight"`
+}
+
+// VariantRouting selects how requests are split across variants.
+// Strategy is "percentage" (random by weight) or "sticky" (by user ID hash);
+// OverrideHeader names a request header that can force a variant by name.
+type VariantRouting struct {
+	Strategy       string `mapstructure:"strategy"`
+	OverrideHeader string `mapstructure:"override_header"`
 }
 
 // JWTConfig holds JWT-related configuration
@@ -54,24 +274,222 @@ type LoggingConfig struct {
 	Format string `mapstructure:"format"`
 }
 
-// CORSConfig holds CORS-related configuration
+// CORSConfig holds CORS-related configuration.
+// AllowedOrigins accepts exact origins, "*" and subdomain patterns such as
+// "https://*.stox.app".
 type CORSConfig struct {
-	AllowedOrigins []string `mapstructure:"allowed_origins"`
-	AllowedMethods []string `mapstructure:"allowed_methods"`
-	AllowedHeaders []string `mapstructure:"allowed_headers"`
+	AllowedOrigins   []string      `mapstructure:"allowed_origins"`
+	AllowedMethods   []string      `mapstructure:"allowed_methods"`
+	AllowedHeaders   []string      `mapstructure:"allowed_headers"`
+	ExposedHeaders   []string      `mapstructure:"exposed_headers"`
+	AllowCredentials bool          `mapstructure:"allow_credentials"`
+	MaxAge           time.Duration `mapstructure:"max_age"`
+}
+
+// SecurityHeadersConfig holds security response header configuration.
+// Empty values fall back to the defaults for the server environment.
+type SecurityHeadersConfig struct {
+	Enabled               bool                   `mapstructure:"enabled"`
+	HSTSMaxAge            time.Duration          `mapstructure:"hsts_max_age"`
+	HSTSIncludeSubdomains bool                   `mapstructure:"hsts_include_subdomains"`
+	ContentTypeOptions    string                 `mapstructure:"content_type_options"`
+	FrameOptions          string                 `mapstructure:"frame_options"`
+	ReferrerPolicy        string                 `mapstructure:"referrer_policy"`
+	ContentSecurityPolicy string                 `mapstructure:"content_security_policy"`
+	Routes                []SecurityHeadersRoute `mapstructure:"routes"`
+}
+
+// SecurityHeadersRoute overrides security headers for a route prefix.
+// A header with an empty value is removed from matching responses.
+type SecurityHeadersRoute struct {
+	PathPrefix string            `mapstructure:"path_prefix"`
+	Headers    map[string]string `mapstructure:"headers"`
+}
+
+// IdempotencyConfig holds Idempotency-Key handling configuration
+type IdempotencyConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`
+	TTL         time.Duration `mapstructure:"ttl"`
+	MaxBodySize int64         `mapstructure:"max_body_size"`
+}
+
+// DeadlinesConfig holds per-route request deadlines. Clients may shorten the
+// deadline with the X-Request-Timeout header; longer values are rejected.
+type DeadlinesConfig struct {
+	Default          time.Duration   `mapstructure:"default"`
+	MinClientTimeout time.Duration   `mapstructure:"min_client_timeout"`
+	WriteGrace       time.Duration   `mapstructure:"write_grace"`
+	Routes           []RouteDeadline `mapstructure:"routes"`
+}
+
+// RouteDeadline sets the deadline for requests under a path prefix
+type RouteDeadline struct {
+	PathPrefix string        `mapstructure:"path_prefix"`
+	Timeout    time.Duration `mapstructure:"timeout"`
+}
+
+// ClientIPConfig holds client IP resolution and IP filtering configuration
+type ClientIPConfig struct {
+	// TrustedProxies lists the CIDRs whose forwarding header is believed,
+	// e.g. the load balancer subnets
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// ForwardedHeader names the header the trusted proxies write:
+	// x-forwarded-for or forwarded. Only that header is read, so a client
+	// can't slip the other one past a proxy that doesn't overwrite it.
+	ForwardedHeader string         `mapstructure:"forwarded_header"`
+	Rules           []IPFilterRule `mapstructure:"rules"`
+}
+
+// IPFilterRule restricts a route prefix by client IP. Deny takes precedence
+// over allow; an empty allow list admits every address not denied.
+type IPFilterRule struct {
+	PathPrefix string   `mapstructure:"path_prefix"`
+	Allow      []string `mapstructure:"allow"`
+	Deny       []string `mapstructure:"deny"`
+}
+
+// FeaturesConfig holds feature flag definitions and maintenance mode settings.
+// Flags can be overridden at runtime through the admin API.
+type FeaturesConfig struct {
+	Maintenance MaintenanceConfig            `mapstructure:"maintenance"`
+	Flags       map[string]FeatureFlagConfig `mapstructure:"flags"`
+}
+
+// MaintenanceConfig holds the response settings used while the "maintenance"
+// flag is enabled
+type MaintenanceConfig struct {
+	RetryAfter  time.Duration `mapstructure:"retry_after"`
+	Message     string        `mapstructure:"message"`
+	ExemptPaths []string      `mapstructure:"exempt_paths"`
+}
+
+// FeatureFlagConfig defines a flag's default state and targeting rules
+type FeatureFlagConfig struct {
+	Enabled     bool                `mapstructure:"enabled"`
+	Description string              `mapstructure:"description"`
+	Targets     []FeatureFlagTarget `mapstructure:"targets"`
+}
+
+// FeatureFlagTarget overrides a flag for matching users or roles. The first
+// matching target wins.
+type FeatureFlagTarget struct {
+	Users   []string `mapstructure:"users"`
+	Roles   []string `mapstructure:"roles"`
+	Enabled bool     `mapstructure:"enabled"`
+}
+
+// TranscodingConfig holds config-driven HTTP-to-gRPC routes. Request and
+// response messages are resolved from the descriptor sets (generated with
+// protoc --descriptor_set_out --include_imports) and from the protos compiled
+// into the gateway.
+type TranscodingConfig struct {
+	DescriptorSets []string           `mapstructure:"descriptor_sets"`
+	Routes         []TranscodingRoute `mapstructure:"routes"`
+	// MaxBodySize caps the JSON body of a request; larger bodies get 413
+	MaxBodySize int64 `mapstructure:"max_body_size"`
+}
+
+// TranscodingRoute maps an HTTP method and path to a gRPC method
+type TranscodingRoute struct {
+	Method string `mapstructure:"method"`
+	// Path is a gorilla/mux path template; {vars} bind to request fields
+	Path string `mapstructure:"path"`
+	// GRPCMethod is the fully qualified method, e.g. "llm.LLMService/Generate"
+	GRPCMethod string `mapstructure:"grpc_method"`
+	// Service names the backend in the services section, e.g. "llm"
+	Service string `mapstructure:"service"`
+	// Body is "*" to bind the JSON body to the whole request, a field name to
+	// bind it to that field, or empty to ignore the body
+	Body string `mapstructure:"body"`
+	// Auth requires a valid bearer token; UserIDField, if set, receives the
+	// authenticated user's ID and cannot be supplied by the client
+	Auth        bool          `mapstructure:"auth"`
+	UserIDField string        `mapstructure:"user_id_field"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+}
+
+// ProxyConfig holds reverse-proxy routes to plain HTTP upstreams
+type ProxyConfig struct {
+	Routes []ProxyRoute `mapstructure:"routes"`
+}
+
+// ProxyRoute forwards every request under PathPrefix to Upstream. The prefix
+// is replaced by RewritePrefix before the request is sent.
+type ProxyRoute struct {
+	PathPrefix      string              `mapstructure:"path_prefix"`
+	Upstream        string              `mapstructure:"upstream"`
+	RewritePrefix   string              `mapstructure:"rewrite_prefix"`
+	Timeout         time.Duration       `mapstructure:"timeout"`
+	Auth            bool                `mapstructure:"auth"`
+	RequestHeaders  HeaderRewriteConfig `mapstructure:"request_headers"`
+	ResponseHeaders HeaderRewriteConfig `mapstructure:"response_headers"`
+}
+
+// HeaderRewriteConfig lists headers to set and to remove
+type HeaderRewriteConfig struct {
+	Add    map[string]string `mapstructure:"add"`
+	Remove []string          `mapstructure:"remove"`
+}
+
+// HealthConfig holds dependency check settings for /readyz. Results are
+// cached for CacheTTL so that frequent probes don't load the backends; each
+// check is bounded by Timeout.
+type HealthConfig struct {
+	CacheTTL time.Duration `mapstructure:"cache_ttl"`
+	Timeout  time.Duration `mapstructure:"timeout"`
+	// CloudFront also checks the distribution with GetDistribution
+	CloudFront bool `mapstructure:"cloudfront"`
+}
+
+// JobsConfig holds image job settings. Job events are kept for
+// EventRetention after the job ends so that clients can resume with
+// Last-Event-ID; at most MaxEvents are kept per job.
+type JobsConfig struct {
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
+	EventRetention    time.Duration `mapstructure:"event_retention"`
+	MaxEvents         int           `mapstructure:"max_events"`
+
+	// Workers run jobs from a queue of at most QueueSize waiting jobs. Each
+	// attempt is bounded by Timeout; failed attempts are retried up to
+	// MaxAttempts with exponential backoff from RetryBackoff.
+	Workers      int           `mapstructure:"workers"`
+	QueueSize    int           `mapstructure:"queue_size"`
+	Timeout      time.Duration `mapstructure:"timeout"`
+	MaxAttempts  int           `mapstructure:"max_attempts"`
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"`
+
+	// StoreDir holds job state so that jobs survive a restart. Finished jobs
+	// are kept for Retention. Jobs and their events live in the process that
+	// accepted them, so replicas must route each user's job requests to the
+	// same instance, e.g. by sticky sessions, or run as a single replica.
+	StoreDir  string        `mapstructure:"store_dir"`
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// CatalogConfig holds image catalog settings. Driver is sqlite, an embedded
+// database at the file named by DSN, or postgres with a connection URL as
+// DSN. Gateway replicas must share a Postgres catalog.
+type CatalogConfig struct {
+	Driver string `mapstructure:"driver"`
+	DSN    string `mapstructure:"dsn"`
+	// MaxOpenConns limits Postgres connections; SQLite always uses one
+	MaxOpenConns int `mapstructure:"max_open_conns"`
 }
 
 // AWSConfig holds AWS-related configuration
 type AWSConfig struct {
-	Region     string            `mapstructure:"region"`
-	S3         S3Config          `mapstructure:"s3"`
-	CloudFront CloudFrontConfig  `mapstructure:"cloudfront"`
+	Region     string           `mapstructure:"region"`
+	S3         S3Config         `mapstructure:"s3"`
+	CloudFront CloudFrontConfig `mapstructure:"cloudfront"`
 }
 
 // S3Config holds S3-related configuration
 type S3Config struct {
 	BucketName string `mapstructure:"bucket_name"`
 	Region     string `mapstructure:"region"`
+
+	// CircuitBreaker fails S3 operations fast while S3 is unhealthy
+	CircuitBreaker CircuitBreakerConfig `mapstructure:"circuit_breaker"`
 }
 
 // CloudFrontConfig holds CloudFront-related configuration
@@ -106,9 +524,46 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	if err := config.validateTransportSecurity(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
+// IsProduction reports whether the gateway runs in a production environment
+func (s *ServerConfig) IsProduction() bool {
+	switch strings.ToLower(s.Environment) {
+	case "production", "prod":
+		return true
+	}
+	return false
+}
+
+// validateTransportSecurity refuses to start a production gateway that would
+// talk to its backends in plaintext
+func (c *Config) validateTransportSecurity() error {
+	if !c.Server.IsProduction() {
+		return nil
+	}
+
+	required := []string{"auth", "image"}
+	for _, route := range c.Transcoding.Routes {
+		required = append(required, route.Service)
+	}
+
+	for _, name := range required {
+		service, ok := c.Services.ByName(name)
+		if !ok {
+			continue
+		}
+		if !service.TLS.Enabled {
+			return fmt.Errorf("services.%s.tls must be enabled in production", name)
+		}
+	}
+	return nil
+}
+
 // setDefaults sets default configuration values
 func setDefaults() {
 	// Server defaults
@@ -116,12 +571,65 @@ func setDefaults() {
 	viper.SetDefault("server.read_timeout", "30s")
 	viper.SetDefault("server.write_timeout", "30s")
 	viper.SetDefault("server.idle_timeout", "60s")
+	viper.SetDefault("server.tls.enabled", false)
+	viper.SetDefault("server.tls.min_version", "1.2")
+	viper.SetDefault("server.tls.client_auth", "none")
+	viper.SetDefault("server.tls.reload_interval", "1m")
+	viper.SetDefault("server.h2c", false)
+	viper.SetDefault("server.shutdown.drain_delay", "5s")
+	viper.SetDefault("server.shutdown.timeout", "30s")
+	viper.SetDefault("server.shutdown.background_timeout", "15s")
 
 	// Service defaults
+	for _, name := range []string{"auth", "image", "llm", "queue", "agent"} {
+		viper.SetDefault("services."+name+".tls.enabled", false)
+		viper.SetDefault("services."+name+".tls.min_version", "1.2")
+		viper.SetDefault("services."+name+".tls.reload_interval", "1m")
+		viper.SetDefault("services."+name+".circuit_breaker.enabled", true)
+		viper.SetDefault("services."+name+".circuit_breaker.failure_threshold", 5)
+		viper.SetDefault("services."+name+".circuit_breaker.open_timeout", "30s")
+		viper.SetDefault("services."+name+".circuit_breaker.half_open_requests", 1)
+		viper.SetDefault("services."+name+".discovery.refresh_interval", "10s")
+		viper.SetDefault("services."+name+".load_balancing.policy", "pick_first")
+		viper.SetDefault("services."+name+".load_balancing.outlier_detection.enabled", false)
+		viper.SetDefault("services."+name+".load_balancing.outlier_detection.consecutive_failures", 5)
+		viper.SetDefault("services."+name+".load_balancing.outlier_detection.base_ejection_time", "30s")
+		viper.SetDefault("services."+name+".load_balancing.outlier_detection.max_ejection_percent", 50)
+		viper.SetDefault("services."+name+".timeout", "30s")
+		viper.SetDefault("services."+name+".keepalive.time", "0s")
+		viper.SetDefault("services."+name+".keepalive.timeout", "20s")
+		viper.SetDefault("services."+name+".compression", "")
+	}
 	viper.SetDefault("services.auth.host", "auth-service")
 	viper.SetDefault("services.auth.port", 50051)
+	viper.SetDefault("services.auth.method_timeouts", []map[string]interface{}{
+		{"method": "ValidateToken", "timeout": "10s"},
+	})
+	viper.SetDefault("services.auth.retry_policies", []map[string]interface{}{
+		{
+			"methods":            []string{"ValidateToken", "GetProfile"},
+			"max_attempts":       3,
+			"initial_backoff":    "100ms",
+			"max_backoff":        "1s",
+			"backoff_multiplier": 2.0,
+			"retryable_codes":    []string{"UNAVAILABLE"},
+		},
+	})
 	viper.SetDefault("services.image.host", "image-service")
 	viper.SetDefault("services.image.port", 50061)
+	viper.SetDefault("services.image.timeout", "60s")
+	// Uploads are capped at 10MB; enhanced output can be larger than input
+	viper.SetDefault("services.image.max_send_message_size", 16*1024*1024)
+	viper.SetDefault("services.image.max_recv_message_size", 32*1024*1024)
+	viper.SetDefault("services.image.routing.strategy", "sticky")
+	viper.SetDefault("services.image.routing.override_header", "X-Image-Variant")
+	viper.SetDefault("services.image.shadow.enabled", false)
+	viper.SetDefault("services.image.shadow.sample_rate", 0.05)
+	viper.SetDefault("services.image.shadow.max_concurrency", 2)
+	viper.SetDefault("services.image.shadow.timeout", "60s")
+	viper.SetDefault("services.image.streaming.enabled", true)
+	viper.SetDefault("services.image.streaming.threshold", 1024*1024)
+	viper.SetDefault("services.image.streaming.chunk_size", 64*1024)
 	viper.SetDefault("services.llm.host", "localhost")
 	viper.SetDefault("services.llm.port", 50052)
 	viper.SetDefault("services.queue.host", "localhost")
@@ -141,15 +649,71 @@ func setDefaults() {
 	// CORS defaults - secure by default
 	viper.SetDefault("cors.allowed_origins", []string{"*"})
 	viper.SetDefault("cors.allowed_methods", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"})
-	viper.SetDefault("cors.allowed_headers", []string{"Content-Type", "Authorization"})
+	viper.SetDefault("cors.allowed_headers", []string{"Content-Type", "Authorization", "X-Job-ID"})
+	viper.SetDefault("cors.exposed_headers", []string{"X-Request-ID", "X-Job-ID"})
+	viper.SetDefault("cors.allow_credentials", true)
+	viper.SetDefault("cors.max_age", "10m")
+
+	// Security headers defaults - values left empty resolve per environment
+	viper.SetDefault("security_headers.enabled", true)
+	viper.SetDefault("security_headers.hsts_include_subdomains", true)
+
+	// Idempotency defaults
+	viper.SetDefault("idempotency.enabled", true)
+	viper.SetDefault("idempotency.ttl", "24h")
+	viper.SetDefault("idempotency.max_body_size", 12*1024*1024) // uploads are capped at 10MB
+
+	// Transcoding defaults
+	viper.SetDefault("transcoding.max_body_size", 1024*1024)
+
+	// Deadline defaults
+	viper.SetDefault("deadlines.default", "30s")
+	viper.SetDefault("deadlines.min_client_timeout", "100ms")
+	viper.SetDefault("deadlines.write_grace", "5s")
+
+	// Client IP defaults - trust only loopback; list load balancers and the
+	// docker-compose network in client_ip.trusted_proxies
+	viper.SetDefault("client_ip.trusted_proxies", []string{"127.0.0.1/32", "::1/128"})
+	viper.SetDefault("client_ip.forwarded_header", "x-forwarded-for")
+
+	// Feature flag defaults
+	viper.SetDefault("features.maintenance.retry_after", "300s")
+	viper.SetDefault("features.maintenance.message", "The service is temporarily unavailable for maintenance")
+	viper.SetDefault("features.maintenance.exempt_paths", []string{"/health", "/livez", "/readyz", "/admin"})
 
 	// AWS defaults
 	viper.SetDefault("aws.region", "us-east-1")
 	viper.SetDefault("aws.s3.bucket_name", "btk-stox-s3")
 	viper.SetDefault("aws.s3.region", "us-east-1")
+	viper.SetDefault("aws.s3.circuit_breaker.enabled", true)
+	viper.SetDefault("aws.s3.circuit_breaker.failure_threshold", 5)
+	viper.SetDefault("aws.s3.circuit_breaker.open_timeout", "30s")
+	viper.SetDefault("aws.s3.circuit_breaker.half_open_requests", 1)
 	viper.SetDefault("aws.cloudfront.distribution_id", "")
 	viper.SetDefault("aws.cloudfront.domain_name", "")
 	viper.SetDefault("aws.cloudfront.region", "us-east-1")
+
+	// Health check defaults
+	viper.SetDefault("health.cache_ttl", "5s")
+	viper.SetDefault("health.timeout", "2s")
+	viper.SetDefault("health.cloudfront", false)
+
+	// Jobs defaults
+	viper.SetDefault("jobs.heartbeat_interval", "15s")
+	viper.SetDefault("jobs.event_retention", "10m")
+	viper.SetDefault("jobs.max_events", 100)
+	viper.SetDefault("jobs.workers", 4)
+	viper.SetDefault("jobs.queue_size", 100)
+	viper.SetDefault("jobs.timeout", "2m")
+	viper.SetDefault("jobs.max_attempts", 3)
+	viper.SetDefault("jobs.retry_backoff", "2s")
+	viper.SetDefault("jobs.store_dir", "./data/jobs")
+	viper.SetDefault("jobs.retention", "24h")
+
+	// Catalog defaults
+	viper.SetDefault("catalog.driver", "sqlite")
+	viper.SetDefault("catalog.dsn", "./data/catalog.db")
+	viper.SetDefault("catalog.max_open_conns", 10)
 }
 
 // GetAuthServiceAddress returns the full address for the auth service
@@ -0,0 +1,169 @@
+package features
+
+import (
+	"sort"
+	"sync"
+
+	"stox-gateway/internal/config"
+)
+
+// Well-known flag names used by the gateway
+const (
+	// Maintenance answers every non-exempt request with 503 when enabled
+	Maintenance = "maintenance"
+	// Uploads gates POST /api/v1/images/upload
+	Uploads = "uploads"
+	// ImageEnhancement gates the enhancement step inside UploadImage
+	ImageEnhancement = "image_enhancement"
+)
+
+// Subject identifies who a flag is evaluated for. Anonymous requests use the
+// zero value and only ever see a flag's default state.
+type Subject struct {
+	UserID string
+	Role   string
+}
+
+// Target overrides a flag for matching users or roles
+type Target struct {
+	Users   []string `json:"users,omitempty"`
+	Roles   []string `json:"roles,omitempty"`
+	Enabled bool     `json:"enabled"`
+}
+
+// Flag is a flag definition: a default state plus targeting rules
+type Flag struct {
+	Enabled     bool     `json:"enabled"`
+	Description string   `json:"description,omitempty"`
+	Targets     []Target `json:"targets,omitempty"`
+}
+
+// FlagState describes a flag as reported by the admin API
+type FlagState struct {
+	Name       string `json:"name"`
+	Flag       Flag   `json:"flag"`
+	Overridden bool   `json:"overridden"`
+}
+
+// matches reports whether the target applies to the subject
+func (t Target) matches(subject Subject) bool {
+	if subject.UserID != "" {
+		for _, user := range t.Users {
+			if user == subject.UserID {
+				return true
+			}
+		}
+	}
+	if subject.Role != "" {
+		for _, role := range t.Roles {
+			if role == subject.Role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// evaluate resolves the flag for a subject
+func (f Flag) evaluate(subject Subject) bool {
+	for _, target := range f.Targets {
+		if target.matches(subject) {
+			return target.Enabled
+		}
+	}
+	return f.Enabled
+}
+
+// Flags holds flag definitions from config together with runtime overrides.
+// Overrides live in process memory: they are not shared between replicas
+// and do not survive a restart. It is safe for concurrent use.
+type Flags struct {
+	mu        sync.RWMutex
+	defaults  map[string]Flag
+	overrides map[string]Flag
+}
+
+// NewFlags creates the flag set from config
+func NewFlags(cfg *config.FeaturesConfig) *Flags {
+	defaults := make(map[string]Flag, len(cfg.Flags))
+	for name, flagCfg := range cfg.Flags {
+		flag := Flag{Enabled: flagCfg.Enabled, Description: flagCfg.Description}
+		for _, target := range flagCfg.Targets {
+			flag.Targets = append(flag.Targets, Target{
+				Users:   target.Users,
+				Roles:   target.Roles,
+				Enabled: target.Enabled,
+			})
+		}
+		defaults[name] = flag
+	}
+
+	return &Flags{
+		defaults:  defaults,
+		overrides: make(map[string]Flag),
+	}
+}
+
+// Enabled reports whether a flag is on for the subject. Unknown flags are
+// treated as enabled so that a missing config entry never switches off a
+// feature that shipped before the flag existed.
+func (f *Flags) Enabled(name string, subject Subject) bool {
+	if f == nil {
+		return true
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if flag, ok := f.overrides[name]; ok {
+		return flag.evaluate(subject)
+	}
+	if flag, ok := f.defaults[name]; ok {
+		return flag.evaluate(subject)
+	}
+	// Maintenance is opt-in, unlike regular features
+	return name != Maintenance
+}
+
+// Set overrides a flag at runtime
+func (f *Flags) Set(name string, flag Flag) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.overrides[name] = flag
+}
+
+// Reset drops a runtime override so the configured definition applies again.
+// It reports whether an override existed.
+func (f *Flags) Reset(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, ok := f.overrides[name]
+	delete(f.overrides, name)
+	return ok
+}
+
+// List returns the effective definition of every known flag, sorted by name
+func (f *Flags) List() []FlagState {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	states := make([]FlagState, 0, len(f.defaults)+len(f.overrides))
+	for name, flag := range f.defaults {
+		if override, ok := f.overrides[name]; ok {
+			states = append(states, FlagState{Name: name, Flag: override, Overridden: true})
+			continue
+		}
+		states = append(states, FlagState{Name: name, Flag: flag})
+	}
+	for name, flag := range f.overrides {
+		if _, ok := f.defaults[name]; !ok {
+			states = append(states, FlagState{Name: name, Flag: flag, Overridden: true})
+		}
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Name < states[j].Name
+	})
+	return states
+}
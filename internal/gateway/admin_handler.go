@@ -0,0 +1,122 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"stox-gateway/internal/features"
+	"stox-gateway/internal/grpcclients"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// AdminHandler handles operational endpoints under /admin
+type AdminHandler struct {
+	flags       *features.Flags
+	imageClient *grpcclients.ImageClient
+	logger      *zap.Logger
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(flags *features.Flags, imageClient *grpcclients.ImageClient, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		flags:       flags,
+		imageClient: imageClient,
+		logger:      logger,
+	}
+}
+
+// ListFlags returns the effective definition of every feature flag
+func (h *AdminHandler) ListFlags(w http.ResponseWriter, r *http.Request) {
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"flags":   h.flags.List(),
+	})
+}
+
+// SetFlag overrides a feature flag at runtime. The override only applies to
+// the gateway instance that serves the request and is lost on restart, so
+// with several replicas it must be sent to each of them; lasting changes
+// belong in the config.
+func (h *AdminHandler) SetFlag(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if name == "" {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Flag name is required")
+		return
+	}
+
+	var flag features.Flag
+	if err := json.NewDecoder(r.Body).Decode(&flag); err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	h.flags.Set(name, flag)
+
+	userID, _ := r.Context().Value(userIDKey).(string)
+	h.logger.Info("Feature flag overridden",
+		zap.String("flag", name),
+		zap.Bool("enabled", flag.Enabled),
+		zap.Int("targets", len(flag.Targets)),
+		zap.String("userID", userID),
+	)
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"flags":   h.flags.List(),
+	})
+}
+
+// ResetFlag drops a runtime override so the configured definition applies again
+func (h *AdminHandler) ResetFlag(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	if !h.flags.Reset(name) {
+		h.writeErrorResponse(w, http.StatusNotFound, "Flag has no runtime override")
+		return
+	}
+
+	userID, _ := r.Context().Value(userIDKey).(string)
+	h.logger.Info("Feature flag override removed",
+		zap.String("flag", name),
+		zap.String("userID", userID),
+	)
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"flags":   h.flags.List(),
+	})
+}
+
+// ShadowStats reports how the shadow image-service compares to the primary
+func (h *AdminHandler) ShadowStats(w http.ResponseWriter, r *http.Request) {
+	stats, ok := h.imageClient.ShadowStats()
+	if !ok {
+		h.writeErrorResponse(w, http.StatusNotFound, "Shadow traffic is not configured")
+		return
+	}
+
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"shadow":  stats,
+	})
+}
+
+// Helper methods
+
+func (h *AdminHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("Failed to encode JSON response", zap.Error(err))
+	}
+}
+
+func (h *AdminHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	response := map[string]interface{}{
+		"success": false,
+		"error":   message,
+	}
+	h.writeJSONResponse(w, statusCode, response)
+}
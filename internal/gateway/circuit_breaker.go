@@ -0,0 +1,25 @@
+package gateway
+
+import (
+	"errors"
+	"math"
+	"net/http"
+
+	"stox-gateway/internal/breaker"
+)
+
+// writeCircuitOpen answers 503 with Retry-After when err comes from an open
+// circuit breaker, and reports whether it did
+func writeCircuitOpen(w http.ResponseWriter, err error) bool {
+	var open *breaker.OpenError
+	if !errors.As(err, &open) {
+		return false
+	}
+
+	retryAfter := int(math.Ceil(open.RetryAfter.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+	writeServiceUnavailable(w, retryAfter, "Service temporarily unavailable, please retry later")
+	return true
+}
@@ -0,0 +1,224 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"stox-gateway/internal/config"
+
+	"go.uber.org/zap"
+)
+
+const clientIPKey contextKey = "client_ip"
+
+// ClientIPKey returns the context key for the resolved client IP (exported for use in handlers)
+func ClientIPKey() contextKey {
+	return clientIPKey
+}
+
+// clientIPFromContext returns the resolved client IP, if any
+func clientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// parsePrefixes parses CIDRs, accepting bare addresses as single-host prefixes
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP address %q: %w", value, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// containsAddr reports whether any prefix contains addr
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseHostAddr parses an address that may carry a port, brackets or quotes,
+// as found in RemoteAddr, X-Forwarded-For and Forwarded for= values
+func parseHostAddr(value string) (netip.Addr, bool) {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if value == "" {
+		return netip.Addr{}, false
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// Forwarding headers that trusted proxies may write
+const (
+	headerXForwardedFor = "x-forwarded-for"
+	headerForwarded     = "forwarded"
+)
+
+// forwardedChain returns the hop addresses recorded by proxies in header,
+// closest client first. Only the header the proxies write is read: a proxy
+// appending to X-Forwarded-For passes a client's Forwarded header through
+// untouched, and vice versa. An unparseable hop (e.g. "unknown" or an
+// obfuscated identifier) ends the chain because nothing before it can be
+// attributed reliably.
+func forwardedChain(r *http.Request, header string) []string {
+	var hops []string
+	if header == headerForwarded {
+		for _, element := range strings.Split(strings.Join(r.Header.Values("Forwarded"), ","), ",") {
+			for _, pair := range strings.Split(element, ";") {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(key, "for") {
+					hops = append(hops, value)
+				}
+			}
+		}
+		return hops
+	}
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	return hops
+}
+
+// resolveClientIP walks the forwarding chain from the nearest hop outwards,
+// skipping trusted proxies, and returns the first address that is not one
+func resolveClientIP(r *http.Request, trusted []netip.Prefix, header string) string {
+	peer, ok := parseHostAddr(r.RemoteAddr)
+	if !ok {
+		return r.RemoteAddr
+	}
+	if !containsAddr(trusted, peer) {
+		return peer.String()
+	}
+
+	client := peer
+	hops := forwardedChain(r, header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHostAddr(hops[i])
+		if !ok {
+			break
+		}
+		client = addr
+		if !containsAddr(trusted, addr) {
+			break
+		}
+	}
+	return client.String()
+}
+
+// ClientIPMiddleware resolves the client IP, honoring the configured
+// forwarding header only when the request arrives through a trusted proxy,
+// and stores it in the request context
+func ClientIPMiddleware(cfg *config.ClientIPConfig) (func(http.Handler) http.Handler, error) {
+	trusted, err := parsePrefixes(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted_proxies: %w", err)
+	}
+	header := strings.ToLower(strings.TrimSpace(cfg.ForwardedHeader))
+	switch header {
+	case "":
+		header = headerXForwardedFor
+	case headerXForwardedFor, headerForwarded:
+	default:
+		return nil, fmt.Errorf("invalid forwarded_header %q: must be x-forwarded-for or forwarded", cfg.ForwardedHeader)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), clientIPKey, resolveClientIP(r, trusted, header))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}, nil
+}
+
+// ipFilterRule is a compiled allow/deny rule for a route prefix
+type ipFilterRule struct {
+	pathPrefix string
+	allow      []netip.Prefix
+	deny       []netip.Prefix
+}
+
+// IPFilterMiddleware applies allow and deny CIDR lists per route prefix using
+// the client IP resolved by ClientIPMiddleware. The most specific prefix wins.
+func IPFilterMiddleware(cfg *config.ClientIPConfig) (func(http.Handler) http.Handler, error) {
+	rules := make([]ipFilterRule, 0, len(cfg.Rules))
+	for _, rule := range cfg.Rules {
+		allow, err := parsePrefixes(rule.Allow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allow list for %s: %w", rule.PathPrefix, err)
+		}
+		deny, err := parsePrefixes(rule.Deny)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny list for %s: %w", rule.PathPrefix, err)
+		}
+		rules = append(rules, ipFilterRule{pathPrefix: rule.PathPrefix, allow: allow, deny: deny})
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return len(rules[i].pathPrefix) > len(rules[j].pathPrefix)
+	})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var rule *ipFilterRule
+			for i := range rules {
+				if strings.HasPrefix(r.URL.Path, rules[i].pathPrefix) {
+					rule = &rules[i]
+					break
+				}
+			}
+			if rule == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			clientIP := clientIPFromContext(r.Context())
+			addr, ok := parseHostAddr(clientIP)
+			permitted := ok &&
+				!containsAddr(rule.deny, addr) &&
+				(len(rule.allow) == 0 || containsAddr(rule.allow, addr))
+			if !permitted {
+				zap.L().Warn("Request rejected by IP filter",
+					zap.String("client_ip", clientIP),
+					zap.String("path", r.URL.Path),
+					zap.String("rule", rule.pathPrefix),
+				)
+				http.Error(w, `{"success": false, "error": "Access denied"}`, http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
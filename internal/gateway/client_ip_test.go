@@ -0,0 +1,162 @@
+package gateway
+
+import (
+	"net/http"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestForwardedChain(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		xff    []string
+		fwd    []string
+		want   []string
+	}{
+		{
+			name:   "x-forwarded-for across header lines",
+			header: headerXForwardedFor,
+			xff:    []string{"203.0.113.7, 10.0.0.1", "10.0.0.2"},
+			want:   []string{"203.0.113.7", " 10.0.0.1", "10.0.0.2"},
+		},
+		{
+			name:   "x-forwarded-for ignores forwarded",
+			header: headerXForwardedFor,
+			xff:    []string{"203.0.113.7"},
+			fwd:    []string{"for=198.51.100.1"},
+			want:   []string{"203.0.113.7"},
+		},
+		{
+			name:   "forwarded for pairs",
+			header: headerForwarded,
+			fwd:    []string{`for=203.0.113.7;proto=https, For="[2001:db8::1]:4711"`, "by=10.0.0.1;for=10.0.0.2"},
+			want:   []string{"203.0.113.7", `"[2001:db8::1]:4711"`, "10.0.0.2"},
+		},
+		{
+			name:   "forwarded ignores x-forwarded-for",
+			header: headerForwarded,
+			xff:    []string{"198.51.100.1"},
+			fwd:    []string{"for=203.0.113.7"},
+			want:   []string{"203.0.113.7"},
+		},
+		{
+			name:   "no header",
+			header: headerForwarded,
+			xff:    []string{"198.51.100.1"},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			for _, value := range tt.xff {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			for _, value := range tt.fwd {
+				r.Header.Add("Forwarded", value)
+			}
+			if got := forwardedChain(r, tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("forwardedChain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveClientIP(t *testing.T) {
+	trusted := []netip.Prefix{
+		netip.MustParsePrefix("127.0.0.1/32"),
+		netip.MustParsePrefix("10.0.0.0/8"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string
+		xff        string
+		fwd        string
+		want       string
+	}{
+		{
+			name:       "untrusted peer ignores headers",
+			remoteAddr: "198.51.100.9:1234",
+			header:     headerXForwardedFor,
+			xff:        "203.0.113.7",
+			want:       "198.51.100.9",
+		},
+		{
+			name:       "trusted peer without header",
+			remoteAddr: "127.0.0.1:1234",
+			header:     headerXForwardedFor,
+			want:       "127.0.0.1",
+		},
+		{
+			name:       "skips trusted hops",
+			remoteAddr: "127.0.0.1:1234",
+			header:     headerXForwardedFor,
+			xff:        "203.0.113.7, 10.1.2.3",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "spoofed hops before the first untrusted one are ignored",
+			remoteAddr: "127.0.0.1:1234",
+			header:     headerXForwardedFor,
+			xff:        "1.2.3.4, 203.0.113.7, 10.1.2.3",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "unparseable hop ends the chain",
+			remoteAddr: "127.0.0.1:1234",
+			header:     headerXForwardedFor,
+			xff:        "203.0.113.7, unknown, 10.1.2.3",
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "forwarded header",
+			remoteAddr: "127.0.0.1:1234",
+			header:     headerForwarded,
+			xff:        "1.2.3.4",
+			fwd:        `for="[2001:db8::1]:4711", for=10.1.2.3`,
+			want:       "2001:db8::1",
+		},
+		{
+			name:       "forwarded header not written by the proxy",
+			remoteAddr: "127.0.0.1:1234",
+			header:     headerXForwardedFor,
+			fwd:        "for=203.0.113.7",
+			want:       "127.0.0.1",
+		},
+		{
+			name:       "ipv4-mapped peer",
+			remoteAddr: "[::ffff:127.0.0.1]:1234",
+			header:     headerXForwardedFor,
+			xff:        "203.0.113.7",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "unparseable peer",
+			remoteAddr: "pipe",
+			header:     headerXForwardedFor,
+			xff:        "203.0.113.7",
+			want:       "pipe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := http.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.fwd != "" {
+				r.Header.Set("Forwarded", tt.fwd)
+			}
+			if got := resolveClientIP(r, trusted, tt.header); got != tt.want {
+				t.Errorf("resolveClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"stox-gateway/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// RequestTimeoutHeader lets clients shorten the deadline of a request
+const RequestTimeoutHeader = "X-Request-Timeout"
+
+// errRequestTimeout rejects X-Request-Timeout values that are not positive or
+// exceed the route timeout
+var errRequestTimeout = errors.New("invalid request timeout")
+
+// parseRequestTimeout accepts a Go duration ("2.5s", "500ms") or a plain
+// number of seconds, up to max when max is positive. Seconds are range
+// checked before converting, as NaN, infinities and huge values would
+// overflow time.Duration.
+func parseRequestTimeout(value string, max time.Duration) (time.Duration, error) {
+	var timeout time.Duration
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if math.IsNaN(seconds) || seconds <= 0 || seconds >= math.MaxInt64/float64(time.Second) {
+			return 0, errRequestTimeout
+		}
+		timeout = time.Duration(seconds * float64(time.Second))
+	} else if timeout, err = time.ParseDuration(value); err != nil {
+		return 0, err
+	}
+
+	if timeout <= 0 || (max > 0 && timeout > max) {
+		return 0, errRequestTimeout
+	}
+	return timeout, nil
+}
+
+// DeadlineMiddleware applies a per-route deadline to the request context so it
+// reaches every gRPC and S3 call, and extends the connection's read and write
+// deadlines with http.ResponseController so that routes allowed to run longer
+// than the server timeouts are not cut off mid-response.
+func DeadlineMiddleware(cfg *config.DeadlinesConfig) func(http.Handler) http.Handler {
+	routes := append([]config.RouteDeadline(nil), cfg.Routes...)
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].PathPrefix) > len(routes[j].PathPrefix)
+	})
+
+	routeTimeout := func(path string) time.Duration {
+		for _, route := range routes {
+			if strings.HasPrefix(path, route.PathPrefix) {
+				return route.Timeout
+			}
+		}
+		return cfg.Default
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := routeTimeout(r.URL.Path)
+
+			if header := strings.TrimSpace(r.Header.Get(RequestTimeoutHeader)); header != "" {
+				// Clients may only shorten the route deadline
+				requested, err := parseRequestTimeout(header, timeout)
+				if err != nil {
+					http.Error(w, `{"success": false, "error": "Invalid X-Request-Timeout header"}`, http.StatusBadRequest)
+					return
+				}
+				if requested < cfg.MinClientTimeout {
+					requested = cfg.MinClientTimeout
+				}
+				if timeout <= 0 || requested < timeout {
+					timeout = requested
+				}
+			}
+
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			deadline, _ := ctx.Deadline()
+
+			// Leave time to write an error response once the context expires
+			rc := http.NewResponseController(w)
+			connDeadline := deadline.Add(cfg.WriteGrace)
+			if err := rc.SetWriteDeadline(connDeadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				zap.L().Warn("Failed to extend write deadline", zap.Error(err))
+			}
+			if err := rc.SetReadDeadline(connDeadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				zap.L().Warn("Failed to extend read deadline", zap.Error(err))
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
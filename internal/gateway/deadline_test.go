@@ -0,0 +1,44 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		max     time.Duration
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "seconds", value: "2.5", max: 30 * time.Second, want: 2500 * time.Millisecond},
+		{name: "duration", value: "500ms", max: 30 * time.Second, want: 500 * time.Millisecond},
+		{name: "route timeout", value: "30s", max: 30 * time.Second, want: 30 * time.Second},
+		{name: "no route timeout", value: "3600", want: time.Hour},
+		{name: "seconds over the route timeout", value: "31", max: 30 * time.Second, wantErr: true},
+		{name: "duration over the route timeout", value: "1m", max: 30 * time.Second, wantErr: true},
+		{name: "zero", value: "0", max: 30 * time.Second, wantErr: true},
+		{name: "negative", value: "-1s", max: 30 * time.Second, wantErr: true},
+		{name: "rounds to zero", value: "1e-12", wantErr: true},
+		{name: "infinity", value: "Inf", wantErr: true},
+		{name: "negative infinity", value: "-Inf", wantErr: true},
+		{name: "not a number", value: "NaN", wantErr: true},
+		{name: "overflowing seconds", value: "1e300", wantErr: true},
+		{name: "overflowing duration", value: "9999999999h", wantErr: true},
+		{name: "garbage", value: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRequestTimeout(tt.value, tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("timeout = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
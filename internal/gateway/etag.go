@@ -0,0 +1,127 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// strongETag formats a digest as a strong entity tag
+func strongETag(sum []byte) string {
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches implements the weak comparison used for If-None-Match, so
+// W/"x" and "x" are considered equal
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeNotModified answers a conditional GET whose ETag still matches
+func writeNotModified(w http.ResponseWriter, etag string) {
+	w.Header().Set("ETag", etag)
+	w.Header().Del("Content-Type")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+}
+
+// checkNotModified sets the ETag on the response and writes 304 if the request
+// already holds it. Handlers that can derive an ETag cheaply call this before
+// building the response body; it reports whether the response is complete.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		writeNotModified(w, etag)
+		return true
+	}
+	return false
+}
+
+// etagRecorder buffers successful JSON responses so that an ETag can be
+// computed over the body. Any other response is written straight through.
+type etagRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	buffering   bool
+	body        bytes.Buffer
+}
+
+func (rec *etagRecorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.wroteHeader = true
+	rec.statusCode = code
+
+	mediaType, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+	rec.buffering = code == http.StatusOK &&
+		mediaType == "application/json" &&
+		rec.Header().Get("ETag") == ""
+	if !rec.buffering {
+		rec.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (rec *etagRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	if rec.buffering {
+		return rec.body.Write(b)
+	}
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rec *etagRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// ETagMiddleware computes strong ETags for JSON GET responses and answers
+// If-None-Match with 304. Responses that already carry an ETag, such as the
+// image list whose ETag listingETag derives from the catalog page, are passed
+// through.
+func ETagMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rec := &etagRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		if !rec.buffering {
+			return
+		}
+
+		sum := sha256.Sum256(rec.body.Bytes())
+		if checkNotModified(w, r, strongETag(sum[:])) {
+			return
+		}
+
+		w.WriteHeader(rec.statusCode)
+		if _, err := w.Write(rec.body.Bytes()); err != nil {
+			zap.L().Error("Failed to write buffered response", zap.Error(err))
+		}
+	})
+}
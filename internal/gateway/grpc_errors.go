@@ -0,0 +1,72 @@
+package gateway
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+)
+
+// writeGRPCError answers a failed backend call. The status code comes from
+// mapGRPCError; error details refine the response:
+//   - BadRequest field violations become a validation-error response
+//   - QuotaFailure answers 429 with the violated quotas
+//   - RetryInfo sets Retry-After
+func writeGRPCError(w http.ResponseWriter, err error) {
+	if writeCircuitOpen(w, err) {
+		return
+	}
+
+	statusCode, message := mapGRPCError(err)
+
+	st, ok := status.FromError(err)
+	if !ok {
+		http.Error(w, message, statusCode)
+		return
+	}
+
+	var fieldErrors []ValidationError
+	var quotaViolations []string
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				fieldErrors = append(fieldErrors, ValidationError{Field: v.GetField(), Message: v.GetDescription()})
+			}
+		case *errdetails.QuotaFailure:
+			for _, v := range d.GetViolations() {
+				if v.GetDescription() != "" {
+					quotaViolations = append(quotaViolations, v.GetDescription())
+				}
+			}
+			statusCode = http.StatusTooManyRequests
+		case *errdetails.RetryInfo:
+			if delay := d.GetRetryDelay(); delay != nil {
+				retryAfter := int(math.Ceil(delay.AsDuration().Seconds()))
+				if retryAfter < 1 {
+					retryAfter = 1
+				}
+				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			}
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		if err := json.NewEncoder(w).Encode(ValidationErrors{Errors: fieldErrors}); err != nil {
+			zap.L().Error("Failed to encode validation errors", zap.Error(err))
+		}
+		return
+	}
+
+	if len(quotaViolations) > 0 {
+		message += ": " + strings.Join(quotaViolations, "; ")
+	}
+	http.Error(w, message, statusCode)
+}
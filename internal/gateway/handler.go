@@ -284,9 +284,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	// Call gRPC service
 	resp, err := h.authClient.Register(r.Context(), req.Email, req.Password, req.FirstName, req.LastName, req.Role)
 	if err != nil {
-		// Map gRPC error to appropriate HTTP status code
-		statusCode, message := mapGRPCError(err)
-		http.Error(w, message, statusCode)
+		writeGRPCError(w, err)
 		return
 	}
 
@@ -329,9 +327,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Call gRPC service
 	resp, err := h.authClient.Login(r.Context(), req.Email, req.Password)
 	if err != nil {
-		// Map gRPC error to appropriate HTTP status code
-		statusCode, message := mapGRPCError(err)
-		http.Error(w, message, statusCode)
+		writeGRPCError(w, err)
 		return
 	}
 
@@ -374,9 +370,7 @@ func (h *AuthHandler) ValidateToken(w http.ResponseWriter, r *http.Request) {
 	// Call gRPC service
 	resp, err := h.authClient.ValidateToken(r.Context(), req.Token)
 	if err != nil {
-		// Map gRPC error to appropriate HTTP status code
-		statusCode, message := mapGRPCError(err)
-		http.Error(w, message, statusCode)
+		writeGRPCError(w, err)
 		return
 	}
 
@@ -414,9 +408,7 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	// Call gRPC service
 	resp, err := h.authClient.GetProfile(r.Context(), userID)
 	if err != nil {
-		// Map gRPC error to appropriate HTTP status code
-		statusCode, message := mapGRPCError(err)
-		http.Error(w, message, statusCode)
+		writeGRPCError(w, err)
 		return
 	}
 
@@ -429,6 +421,16 @@ func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// chooseImageVariant selects the image service variant for a request,
+// honoring the client's override header when one is configured
+func chooseImageVariant(r *http.Request, imageClient *grpcclients.ImageClient, userID string) string {
+	var override string
+	if header := imageClient.OverrideHeader(); header != "" {
+		override = r.Header.Get(header)
+	}
+	return imageClient.ChooseVariant(userID, override)
+}
+
 // ProcessImage handles image processing requests
 func (h *ImageHandler) ProcessImage(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -475,12 +477,15 @@ func (h *ImageHandler) ProcessImage(w http.ResponseWriter, r *http.Request) {
 	// Get optional product name from form
 	productName := r.FormValue("product_name")
 
+	// Pick the image service variant and record it for comparison
+	variant := chooseImageVariant(r, h.imageClient, "")
+	ctx := grpcclients.WithImageVariant(r.Context(), variant)
+	w.Header().Set("X-Image-Variant", variant)
+
 	// Call gRPC service
-	resp, err := h.imageClient.ProcessImage(r.Context(), imageData, mimeType, productName)
+	resp, err := h.imageClient.ProcessImage(ctx, imageData, mimeType, productName)
 	if err != nil {
-		// Map gRPC error to appropriate HTTP status code
-		statusCode, message := mapGRPCError(err)
-		http.Error(w, message, statusCode)
+		writeGRPCError(w, err)
 		return
 	}
 
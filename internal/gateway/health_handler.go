@@ -0,0 +1,74 @@
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"stox-gateway/internal/health"
+
+	"go.uber.org/zap"
+)
+
+// HealthHandler serves the Kubernetes liveness and readiness probes
+type HealthHandler struct {
+	checker *health.Checker
+	ready   func() bool
+	logger  *zap.Logger
+}
+
+// NewHealthHandler creates a new health handler. ready reports whether the
+// gateway is shutting down; nil means it never is.
+func NewHealthHandler(checker *health.Checker, ready func() bool, logger *zap.Logger) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+		ready:   ready,
+		logger:  logger,
+	}
+}
+
+// Livez reports that the process is up. It deliberately ignores dependencies
+// so that a backend outage never gets healthy pods restarted.
+func (h *HealthHandler) Livez(w http.ResponseWriter, r *http.Request) {
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"status": health.StatusOK,
+	})
+}
+
+// Readyz reports whether the gateway can serve traffic, with the status of
+// each dependency
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	if h.ready != nil && !h.ready() {
+		h.writeJSONResponse(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status": "shutting_down",
+		})
+		return
+	}
+
+	report := h.checker.Check()
+	statusCode := http.StatusOK
+	if !report.Healthy() {
+		statusCode = http.StatusServiceUnavailable
+	}
+	for name, result := range report.Checks {
+		if result.Status != health.StatusOK {
+			h.logger.Warn("Dependency check failed",
+				zap.String("check", name),
+				zap.Bool("optional", result.Optional),
+				zap.String("error", result.Error),
+			)
+		}
+	}
+	h.writeJSONResponse(w, statusCode, report)
+}
+
+// Helper methods
+
+func (h *HealthHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("Failed to encode JSON response", zap.Error(err))
+	}
+}
@@ -0,0 +1,274 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"stox-gateway/internal/config"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client-chosen key
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// maxIdempotencyKeyLength bounds the size of stored keys
+	maxIdempotencyKeyLength = 255
+
+	// idempotencySweepInterval is how often expired entries are purged
+	idempotencySweepInterval = time.Minute
+)
+
+// idempotentResponse is a stored response replayed for retried requests
+type idempotentResponse struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+}
+
+// idempotencyEntry tracks a key from the first request until it expires
+type idempotencyEntry struct {
+	fingerprint string
+	response    *idempotentResponse // nil while the first request is in flight
+	expiresAt   time.Time
+}
+
+// idempotencyStore is an in-memory store of responses keyed by user and key
+type idempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]*idempotencyEntry
+	ttl       time.Duration
+	lastSweep time.Time
+}
+
+// newIdempotencyStore creates an empty store with the given entry TTL
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		entries:   make(map[string]*idempotencyEntry),
+		ttl:       ttl,
+		lastSweep: time.Now(),
+	}
+}
+
+// begin claims a key for a new request. If the key is already known, the
+// existing entry is returned instead and the caller must not run the request.
+func (s *idempotencyStore) begin(key, fingerprint string) (*idempotencyEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > idempotencySweepInterval {
+		for k, entry := range s.entries {
+			if entry.response != nil && now.After(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if entry, ok := s.entries[key]; ok && (entry.response == nil || now.Before(entry.expiresAt)) {
+		// Copy so the caller can read it without holding the lock
+		existing := *entry
+		return &existing, false
+	}
+
+	s.entries[key] = &idempotencyEntry{fingerprint: fingerprint}
+	return nil, true
+}
+
+// complete stores the response for a claimed key
+func (s *idempotencyStore) complete(key string, response *idempotentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry, ok := s.entries[key]; ok {
+		entry.response = response
+		entry.expiresAt = time.Now().Add(s.ttl)
+	}
+}
+
+// release forgets a claimed key so that the client can retry it
+func (s *idempotencyStore) release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+}
+
+// idempotencyRecorder writes through to the client while keeping a copy of
+// the response for later replay
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(code int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.statusCode = code
+	rec.wroteHeader = true
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// requestFingerprint hashes the method, path and payload of a request.
+// Multipart bodies are hashed part by part because clients usually pick a new
+// boundary on every retry even when the content is unchanged.
+func requestFingerprint(r *http.Request, body []byte) (string, error) {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" || params["boundary"] == "" {
+		io.WriteString(h, mediaType+"\n")
+		h.Write(body)
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		io.WriteString(h, part.FormName()+"\x00"+part.FileName()+"\x00"+part.Header.Get("Content-Type")+"\x00")
+		if _, err := io.Copy(h, part); err != nil {
+			return "", err
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeIdempotencyError writes a JSON error in the gateway's error shape
+func writeIdempotencyError(w http.ResponseWriter, statusCode int, message string) {
+	http.Error(w, `{"success": false, "error": "`+message+`"}`, statusCode)
+}
+
+// IdempotencyMiddleware honors the Idempotency-Key header on POST requests.
+// The first response for a user and key is stored for the configured TTL and
+// replayed on retries; reusing a key with a different payload returns 409.
+// It must run after AuthMiddleware on authenticated routes so that keys are
+// scoped per user.
+func IdempotencyMiddleware(cfg *config.IdempotencyConfig) func(http.Handler) http.Handler {
+	if !cfg.Enabled {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	store := newIdempotencyStore(cfg.TTL)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := strings.TrimSpace(r.Header.Get(IdempotencyKeyHeader))
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				writeIdempotencyError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			logger := zap.L().With(zap.String("idempotency_key", key))
+
+			// Buffer the body so it can be fingerprinted and then handed on
+			body, err := io.ReadAll(io.LimitReader(r.Body, cfg.MaxBodySize+1))
+			if err != nil {
+				writeIdempotencyError(w, http.StatusBadRequest, "Failed to read request body")
+				return
+			}
+			if int64(len(body)) > cfg.MaxBodySize {
+				writeIdempotencyError(w, http.StatusRequestEntityTooLarge, "Request body too large")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			fingerprint, err := requestFingerprint(r, body)
+			if err != nil {
+				writeIdempotencyError(w, http.StatusBadRequest, "Malformed request body")
+				return
+			}
+
+			// Anonymous routes such as registration share one scope
+			userID, _ := r.Context().Value(userIDKey).(string)
+			storeKey := userID + "\x00" + r.URL.Path + "\x00" + key
+
+			existing, claimed := store.begin(storeKey, fingerprint)
+			if !claimed {
+				switch {
+				case existing.fingerprint != fingerprint:
+					logger.Warn("Idempotency key reused with a different payload")
+					writeIdempotencyError(w, http.StatusConflict, "Idempotency-Key was already used with a different request payload")
+				case existing.response == nil:
+					writeIdempotencyError(w, http.StatusConflict, "A request with this Idempotency-Key is still in progress")
+				default:
+					logger.Info("Replaying stored response for idempotency key")
+					for name, values := range existing.response.header {
+						// Keep per-request headers such as X-Request-ID that
+						// outer middleware has already set for this attempt
+						if _, ok := w.Header()[name]; ok {
+							continue
+						}
+						w.Header()[name] = append([]string(nil), values...)
+					}
+					w.Header().Set("Idempotent-Replayed", "true")
+					w.WriteHeader(existing.response.statusCode)
+					if _, err := w.Write(existing.response.body); err != nil {
+						logger.Error("Failed to write replayed response", zap.Error(err))
+					}
+				}
+				return
+			}
+
+			rec := &idempotencyRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+			completed := false
+			defer func() {
+				if !completed {
+					store.release(storeKey)
+				}
+			}()
+
+			next.ServeHTTP(rec, r)
+
+			// Server errors are not stored so that the client can retry them
+			if rec.statusCode >= http.StatusInternalServerError {
+				return
+			}
+			store.complete(storeKey, &idempotentResponse{
+				statusCode: rec.statusCode,
+				header:     w.Header().Clone(),
+				body:       rec.body.Bytes(),
+			})
+			completed = true
+		})
+	}
+}
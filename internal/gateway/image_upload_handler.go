@@ -3,19 +3,37 @@ package gateway
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"stox-gateway/internal/aws"
+	"stox-gateway/internal/breaker"
+	"stox-gateway/internal/catalog"
+	"stox-gateway/internal/features"
 	"stox-gateway/internal/grpcclients"
+	"stox-gateway/internal/jobs"
+	"stox-gateway/internal/lifecycle"
+	pb "stox-gateway/internal/proto/image-service"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	_ "golang.org/x/image/webp"
+	"google.golang.org/grpc/codes"
 )
 
 // ImageUploadHandler handles image upload operations with S3 integration
@@ -24,11 +42,21 @@ type ImageUploadHandler struct {
 	cloudFront      *aws.CloudFrontService
 	imageClient     *grpcclients.ImageClient
 	authClient      *grpcclients.AuthClient
+	flags           *features.Flags
+	lifecycle       *lifecycle.Manager
+	jobs            *jobs.Pool
+	catalog         *catalog.Catalog
 	logger          *zap.Logger
 	maxFileSize     int64  // Maximum file size in bytes (e.g., 10MB)
 	allowedFormats  []string
 }
 
+// JobIDHeader names an upload's enhancement job. Clients may set it to choose
+// the job ID.
+const JobIDHeader = "X-Job-ID"
+
+var jobIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{8,64}$`)
+
 // ImageUploadRequest represents the upload request structure
 type ImageUploadRequest struct {
 	UserID      string `json:"userId"`
@@ -43,7 +71,19 @@ type ImageUploadResponse struct {
 	EnhancedImage  *aws.ImageUploadResult  `json:"enhancedImage,omitempty"`
 	CloudFrontURL  string                  `json:"cloudFrontUrl,omitempty"`
 	EnhancedURL    string                  `json:"enhancedUrl,omitempty"`
+	ImageID        string                  `json:"imageId,omitempty"`
 	ProcessingID   string                  `json:"processingId,omitempty"`
+	JobURL         string                  `json:"jobUrl,omitempty"`
+	EnhancementVariant string              `json:"enhancementVariant,omitempty"`
+}
+
+// EnhancementJob is the payload of an image enhancement job
+type EnhancementJob struct {
+	// ImageID is the catalog entry that records the enhanced image
+	ImageID     string                 `json:"imageId,omitempty"`
+	Original    *aws.ImageUploadResult `json:"original"`
+	ProductName string                 `json:"productName,omitempty"`
+	Variant     string                 `json:"variant,omitempty"`
 }
 
 // ImageProcessResponse represents the image processing response
@@ -56,12 +96,35 @@ type ImageProcessResponse struct {
 	EnhancedImage *aws.ImageUploadResult `json:"enhancedImage,omitempty"`
 }
 
+// CatalogImage is a catalog entry in API responses
+type CatalogImage struct {
+	ID          string            `json:"id"`
+	Key         string            `json:"key,omitempty"`
+	URL         string            `json:"url,omitempty"`
+	EnhancedKey string            `json:"enhancedKey,omitempty"`
+	EnhancedURL string            `json:"enhancedUrl,omitempty"`
+	Variants    map[string]string `json:"variants,omitempty"` // variant name to URL
+	ContentType string            `json:"contentType,omitempty"`
+	Size        int64             `json:"size"`
+	Width       int               `json:"width,omitempty"`
+	Height      int               `json:"height,omitempty"`
+	ProductName string            `json:"productName,omitempty"`
+	Status      catalog.Status    `json:"status"`
+	JobID       string            `json:"jobId,omitempty"`
+	CreatedAt   time.Time         `json:"createdAt"`
+	UpdatedAt   time.Time         `json:"updatedAt"`
+}
+
 // NewImageUploadHandler creates a new image upload handler
 func NewImageUploadHandler(
 	s3Service *aws.S3Service,
 	cloudFront *aws.CloudFrontService,
 	imageClient *grpcclients.ImageClient,
 	authClient *grpcclients.AuthClient,
+	flags *features.Flags,
+	lifecycleManager *lifecycle.Manager,
+	jobPool *jobs.Pool,
+	imageCatalog *catalog.Catalog,
 	logger *zap.Logger,
 ) *ImageUploadHandler {
 	return &ImageUploadHandler{
@@ -69,6 +132,10 @@ func NewImageUploadHandler(
 		cloudFront:     cloudFront,
 		imageClient:    imageClient,
 		authClient:     authClient,
+		flags:          flags,
+		lifecycle:      lifecycleManager,
+		jobs:           jobPool,
+		catalog:        imageCatalog,
 		logger:         logger,
 		maxFileSize:    10 * 1024 * 1024, // 10MB
 		allowedFormats: []string{"image/jpeg", "image/jpg", "image/png", "image/webp"},
@@ -76,7 +143,8 @@ func NewImageUploadHandler(
 }
 
 // UploadImage handles the image upload process
-// Flow: Frontend -> Gateway -> S3 (original) -> Response -> Gateway -> Image Service -> S3 (enhanced)
+// Flow: Frontend -> Gateway -> S3 (original) -> 202 with job ID
+// Enhancement job: Gateway -> Image Service -> S3 (enhanced)
 func (h *ImageUploadHandler) UploadImage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	
@@ -90,6 +158,20 @@ func (h *ImageUploadHandler) UploadImage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	
+	jobID := r.Header.Get(JobIDHeader)
+	if jobID != "" && !jobIDPattern.MatchString(jobID) {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid X-Job-ID header")
+		return
+	}
+	// Job IDs are scoped to the user, so this only finds the user's own job.
+	// Checking before the upload saves storing an image for a rejected job.
+	if jobID != "" {
+		if _, err := h.jobs.Get(jobID, userID); err == nil {
+			h.writeErrorResponse(w, http.StatusConflict, "Job ID already in use")
+			return
+		}
+	}
+	
 	// Parse multipart form
 	err = r.ParseMultipartForm(h.maxFileSize)
 	if err != nil {
@@ -117,6 +199,9 @@ func (h *ImageUploadHandler) UploadImage(w http.ResponseWriter, r *http.Request)
 	// Get product name from form (optional)
 	productName := r.FormValue("productName")
 	
+	// Dimensions are recorded in the catalog; undecodable images get 0x0
+	width, height := imageDimensions(file)
+	
 	// Reset file pointer to beginning
 	if _, err := file.Seek(0, 0); err != nil {
 		h.logger.Error("Failed to reset file pointer", zap.Error(err))
@@ -134,6 +219,9 @@ func (h *ImageUploadHandler) UploadImage(w http.ResponseWriter, r *http.Request)
 	)
 	if err != nil {
 		h.logger.Error("Failed to upload original image to S3", zap.Error(err))
+		if writeCircuitOpen(w, err) {
+			return
+		}
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to upload image")
 		return
 	}
@@ -146,60 +234,283 @@ func (h *ImageUploadHandler) UploadImage(w http.ResponseWriter, r *http.Request)
 	// Generate CloudFront URL for the uploaded image
 	cloudFrontURL := h.cloudFront.GetImageURL(originalResult.Key)
 
-	// Process image enhancement synchronously
-	h.logger.Info("Starting synchronous image enhancement", zap.String("userID", userID))
-	enhancedResult, err := h.ProcessImageEnhancement(ctx, userID, originalResult, productName)
-	
-	var enhancedCloudFrontURL string
-	if err != nil {
-		h.logger.Error("Failed to process image enhancement", zap.Error(err))
-		// Continue without enhanced image - user still has original
-	} else {
-		enhancedCloudFrontURL = enhancedResult.EnhancedURL
-		h.logger.Info("Successfully created enhanced image", 
-			zap.String("userID", userID),
-			zap.String("enhancedURL", enhancedCloudFrontURL),
-		)
+	// Enhancement can be switched off at runtime, e.g. during a model rollout
+	enhancementEnabled := h.flags.Enabled(features.ImageEnhancement, subjectFromRequest(r))
+	// Don't queue work for an image service whose circuit is open
+	enhancementUnavailable := enhancementEnabled && h.imageClient.CircuitOpen()
+	
+	// Catalog the image. Without a catalog entry the upload is undone, so
+	// that the catalog and S3 stay in step.
+	img := &catalog.Image{
+		ID:          uuid.New().String(),
+		Owner:       userID,
+		OriginalKey: originalResult.Key,
+		ContentType: originalResult.ContentType,
+		Size:        originalResult.Size,
+		Width:       width,
+		Height:      height,
+		ProductName: productName,
+		Status:      catalog.StatusUploaded,
+	}
+	if enhancementEnabled && !enhancementUnavailable {
+		if jobID == "" {
+			jobID = generateRequestID()
+		}
+		img.Status = catalog.StatusProcessing
+		img.JobID = jobID
 	}
-
-	// Prepare response
+	if err := h.catalog.Create(ctx, img); err != nil {
+		h.logger.Error("Failed to catalog image, removing upload", zap.Error(err))
+		if err := h.s3Service.DeleteImage(context.WithoutCancel(ctx), originalResult.Key); err != nil {
+			h.logger.Error("Failed to remove uncatalogued image", zap.String("key", originalResult.Key), zap.Error(err))
+		}
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to upload image")
+		return
+	}
+	
 	response := ImageUploadResponse{
 		Success:       true,
 		OriginalImage: originalResult,
 		CloudFrontURL: cloudFrontURL,
+		ImageID:       img.ID,
 	}
-
-	if enhancedResult != nil {
-		response.Message = "Image uploaded and enhanced successfully"
-		response.EnhancedImage = enhancedResult.EnhancedImage
-		response.EnhancedURL = enhancedCloudFrontURL
+	statusCode := http.StatusOK
+	
+	if !enhancementEnabled {
+		h.logger.Info("Image enhancement disabled by feature flag", zap.String("userID", userID))
+		response.Message = "Image uploaded successfully. Enhancement is currently disabled"
+	} else if enhancementUnavailable {
+		h.logger.Warn("Image enhancement skipped, image service circuit is open", zap.String("userID", userID))
+		response.Message = "Image uploaded successfully. Enhancement is temporarily unavailable"
 	} else {
-		response.Message = "Image uploaded successfully. Enhancement failed - please try again"
+		// Pick the image service variant (canary routing) for this user
+		variant := chooseImageVariant(r, h.imageClient, userID)
+		
+		job, err := h.jobs.Submit(jobID, userID, EnhancementJob{
+			ImageID:     img.ID,
+			Original:    originalResult,
+			ProductName: productName,
+			Variant:     variant,
+		})
+		if err != nil && !errors.Is(err, jobs.ErrExists) {
+			h.markNotQueued(ctx, userID, img.ID)
+		}
+		switch {
+		case errors.Is(err, jobs.ErrExists):
+			// A concurrent upload took the ID since the check above; undo
+			// this upload as the client will retry it
+			h.removeUpload(ctx, userID, img)
+			h.writeErrorResponse(w, http.StatusConflict, "Job ID already in use")
+			return
+		case errors.Is(err, jobs.ErrQueueFull):
+			h.logger.Warn("Image enhancement skipped, job queue is full", zap.String("userID", userID))
+			response.Message = "Image uploaded successfully. Enhancement is temporarily unavailable"
+		case err != nil:
+			h.logger.Error("Failed to queue image enhancement", zap.Error(err))
+			response.Message = "Image uploaded successfully. Enhancement failed - please try again"
+		default:
+			h.logger.Info("Queued image enhancement",
+				zap.String("userID", userID),
+				zap.String("jobID", job.ID),
+				zap.String("variant", variant),
+			)
+			statusCode = http.StatusAccepted
+			response.Message = "Image uploaded successfully. Enhancement is in progress"
+			response.ProcessingID = job.ID
+			response.JobURL = "/api/v1/images/jobs/" + job.ID
+			response.EnhancementVariant = variant
+			w.Header().Set("Location", response.JobURL)
+			w.Header().Set(JobIDHeader, job.ID)
+			w.Header().Set("X-Image-Variant", variant)
+		}
 	}
 	
-	h.writeJSONResponse(w, http.StatusOK, response)
+	h.writeJSONResponse(w, statusCode, response)
+}
+
+// RunEnhancementJob runs one attempt of an enhancement job; it is the
+// handler of the image job pool
+func (h *ImageUploadHandler) RunEnhancementJob(ctx context.Context, job jobs.Job, report func(jobs.Progress)) (interface{}, error) {
+	var payload EnhancementJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil || payload.Original == nil {
+		return nil, jobs.Permanent(errors.New("invalid enhancement job"))
+	}
+	
+	ctx = grpcclients.WithImageVariant(ctx, payload.Variant)
+	result, err := h.ProcessImageEnhancement(ctx, job.Owner, payload.Original, payload.ProductName, report)
+	if err != nil {
+		h.logger.Error("Image enhancement attempt failed", zap.String("jobID", job.ID), zap.Error(err))
+		message, retryable := enhancementFailure(err)
+		if !retryable {
+			return nil, jobs.Permanent(errors.New(message))
+		}
+		return nil, errors.New(message)
+	}
+	
+	if payload.ImageID != "" {
+		if err := h.recordEnhanced(ctx, job.Owner, payload.ImageID, result.EnhancedImage.Key); err != nil {
+			return nil, err
+		}
+	}
+	
+	result.ProcessingID = job.ID
+	return result, nil
+}
+
+// EnhancementJobFinished records failed enhancement jobs in the catalog
+func (h *ImageUploadHandler) EnhancementJobFinished(job jobs.Job) {
+	var payload EnhancementJob
+	if job.Status != jobs.StatusFailed || json.Unmarshal(job.Payload, &payload) != nil || payload.ImageID == "" {
+		return
+	}
+	
+	_, err := h.catalog.Update(context.Background(), job.Owner, payload.ImageID, func(img *catalog.Image) error {
+		if img.JobID == job.ID && img.Status == catalog.StatusProcessing {
+			img.Status = catalog.StatusFailed
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, catalog.ErrNotFound) {
+		h.logger.Error("Failed to record failed enhancement", zap.String("jobID", job.ID), zap.Error(err))
+	}
+}
+
+// recordEnhanced links an enhanced image to its catalog entry. If that fails
+// the enhanced upload is removed again, and a replaced enhanced image is
+// removed once the link is recorded.
+func (h *ImageUploadHandler) recordEnhanced(ctx context.Context, userID, imageID, key string) error {
+	var replaced string
+	_, err := h.catalog.Update(ctx, userID, imageID, func(img *catalog.Image) error {
+		if img.Status == catalog.StatusDeleting {
+			return catalog.ErrNotFound
+		}
+		if img.EnhancedKey != key {
+			replaced = img.EnhancedKey
+		}
+		img.EnhancedKey = key
+		img.Status = catalog.StatusEnhanced
+		return nil
+	})
+	
+	cleanup := context.WithoutCancel(ctx)
+	if err != nil {
+		h.logger.Error("Failed to catalog enhanced image, removing upload", zap.String("imageID", imageID), zap.Error(err))
+		if err := h.s3Service.DeleteImage(cleanup, key); err != nil {
+			h.logger.Error("Failed to remove uncatalogued image", zap.String("key", key), zap.Error(err))
+		}
+		if errors.Is(err, catalog.ErrNotFound) {
+			return jobs.Permanent(errors.New("Image was deleted during enhancement"))
+		}
+		return errors.New("Failed to record enhanced image")
+	}
+	
+	if replaced != "" {
+		if err := h.s3Service.DeleteImage(cleanup, replaced); err != nil {
+			h.logger.Warn("Failed to remove replaced enhanced image", zap.String("key", replaced), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// deleteCatalogued deletes an image and all of its objects, returning their
+// keys. The image is marked as deleting first, so that no keys are added
+// while the objects are deleted outside of any transaction. If deleting an
+// object fails the image stays marked until the client retries or
+// catalog-reconcile finishes the delete.
+func (h *ImageUploadHandler) deleteCatalogued(ctx context.Context, userID, imageID string) ([]string, error) {
+	img, err := h.catalog.Update(ctx, userID, imageID, func(img *catalog.Image) error {
+		img.Status = catalog.StatusDeleting
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	keys := img.Keys()
+	for _, key := range keys {
+		if err := h.s3Service.DeleteImage(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	err = h.catalog.Delete(ctx, userID, imageID, nil)
+	if err != nil && !errors.Is(err, catalog.ErrNotFound) {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// removeUpload undoes an upload that was rejected after it was stored
+func (h *ImageUploadHandler) removeUpload(ctx context.Context, userID string, img *catalog.Image) {
+	ctx = context.WithoutCancel(ctx)
+	if err := h.catalog.Delete(ctx, userID, img.ID, nil); err != nil {
+		h.logger.Error("Failed to remove rejected image from catalog", zap.String("imageID", img.ID), zap.Error(err))
+		return
+	}
+	if err := h.s3Service.DeleteImage(ctx, img.OriginalKey); err != nil {
+		h.logger.Error("Failed to remove rejected upload", zap.String("key", img.OriginalKey), zap.Error(err))
+	}
+}
+
+// markNotQueued records that an image's enhancement job could not be queued
+func (h *ImageUploadHandler) markNotQueued(ctx context.Context, userID, imageID string) {
+	_, err := h.catalog.Update(context.WithoutCancel(ctx), userID, imageID, func(img *catalog.Image) error {
+		img.Status = catalog.StatusUploaded
+		img.JobID = ""
+		return nil
+	})
+	if err != nil {
+		h.logger.Error("Failed to update catalog after queueing failed", zap.String("imageID", imageID), zap.Error(err))
+	}
+}
+
+// enhancementFailure returns the message shown to clients for a failed
+// enhancement and whether another attempt may succeed
+func enhancementFailure(err error) (string, bool) {
+	if errors.Is(err, breaker.ErrOpen) {
+		return "Enhancement is temporarily unavailable", true
+	}
+	
+	var callErr *grpcclients.Error
+	if errors.As(err, &callErr) {
+		switch callErr.Status.Code() {
+		// ResourceExhausted is usually an enhanced image over the receive
+		// limit, which another attempt would only download again
+		case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange, codes.NotFound, codes.PermissionDenied, codes.Unimplemented, codes.ResourceExhausted:
+			return "Image service rejected the image: " + callErr.Status.Message(), false
+		}
+		return "Image service failed to enhance the image", true
+	}
+	return "Enhancement failed", true
 }
 
 // ProcessImageEnhancement handles the image enhancement process
-func (h *ImageUploadHandler) ProcessImageEnhancement(ctx context.Context, userID string, originalResult *aws.ImageUploadResult, productName string) (*ImageProcessResponse, error) {
+func (h *ImageUploadHandler) ProcessImageEnhancement(ctx context.Context, userID string, originalResult *aws.ImageUploadResult, productName string, report func(jobs.Progress)) (*ImageProcessResponse, error) {
 	h.logger.Info("Starting image enhancement process", 
 		zap.String("userID", userID),
 		zap.String("originalKey", originalResult.Key),
 	)
 	
 	// Download the original image from S3 for processing
+	report(jobs.Progress{Stage: "downloading", Percent: 5})
 	imageData, err := h.s3Service.DownloadImage(ctx, originalResult.Key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download original image: %w", err)
 	}
 	
-	// Call image service for enhancement
-	processResponse, err := h.imageClient.ProcessImage(ctx, imageData, originalResult.ContentType, productName)
+	// Call image service for enhancement. Its progress maps to 20-90%.
+	report(jobs.Progress{Stage: "enhancing", Percent: 20})
+	processResponse, err := h.imageClient.ProcessImageWithProgress(ctx, imageData, originalResult.ContentType, productName,
+		func(update *pb.ProcessImageProgress) {
+			percent := min(max(int(update.Percent), 0), 100)
+			report(jobs.Progress{Stage: update.Stage, Percent: 20 + percent*70/100, Message: update.Message})
+		},
+	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to process image: %w", err)
 	}
 	
 	// Upload enhanced image to S3
+	report(jobs.Progress{Stage: "saving", Percent: 90})
 	enhancedFileName := fmt.Sprintf("enhanced_%s", originalResult.FileName)
 	enhancedResult, err := h.s3Service.UploadEnhancedImage(
 		ctx,
@@ -226,7 +537,6 @@ func (h *ImageUploadHandler) ProcessImageEnhancement(ctx context.Context, userID
 		Message:       "Image successfully enhanced",
 		OriginalURL:   originalURL,
 		EnhancedURL:   enhancedURL,
-		ProcessingID:  fmt.Sprintf("proc_%d", time.Now().Unix()),
 		EnhancedImage: enhancedResult,
 	}, nil
 }
@@ -306,7 +616,17 @@ func (h *ImageUploadHandler) extractUserIDFromToken(r *http.Request) (string, er
 	return validateResponse.UserId, nil
 }
 
-// GetUserImages returns all images for a specific user
+// Page sizes of GET /api/v1/images/list; larger limits are capped
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// GetUserImages returns a page of a user's images. Query parameters:
+// limit and cursor page through the images, type, product, from and to
+// filter them, and order (desc or asc) sorts them by upload time, newest
+// first by default. type=enhanced keeps images that have an enhanced version
+// and type=original those that have only their original.
 func (h *ImageUploadHandler) GetUserImages(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	
@@ -318,33 +638,53 @@ func (h *ImageUploadHandler) GetUserImages(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	
-	// List user images from S3
-	imageKeys, err := h.s3Service.ListUserImages(ctx, userID)
+	opts, err := parseListOptions(r)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	
+	// List user images from the catalog
+	page, err := h.catalog.Page(ctx, userID, opts)
+	if errors.Is(err, catalog.ErrInvalidCursor) {
+		h.writeErrorResponse(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
 	if err != nil {
 		h.logger.Error("Failed to list user images", zap.Error(err))
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve images")
 		return
 	}
 	
+	// The listing state alone determines the response, so unchanged
+	// libraries are answered with 304 before any URLs are generated
+	if checkNotModified(w, r, listingETag(page.Images, page.Total)) {
+		return
+	}
+	
 	// Generate CloudFront URLs
-	var imageURLs []map[string]string
-	for _, key := range imageKeys {
-		imageURLs = append(imageURLs, map[string]string{
-			"key": key,
-			"url": h.cloudFront.GetImageURL(key),
-		})
+	catalogImages := make([]CatalogImage, 0, len(page.Images))
+	for _, img := range page.Images {
+		catalogImages = append(catalogImages, h.catalogImage(img))
 	}
 	
 	response := map[string]interface{}{
 		"success": true,
-		"images":  imageURLs,
-		"count":   len(imageURLs),
+		"images":  catalogImages,
+		"count":   len(catalogImages),
+		"total":   page.Total,
+		"limit":   opts.Limit,
+	}
+	if page.NextCursor != "" {
+		response["nextCursor"] = page.NextCursor
 	}
 	
 	h.writeJSONResponse(w, http.StatusOK, response)
 }
 
-// DeleteUserImage deletes a specific image for a user
+// DeleteUserImage deletes a specific image for a user. imageId is a catalog
+// ID or an S3 key. Deleting by ID or original key removes all of the image's
+// objects; deleting by enhanced key removes only the enhanced version.
 func (h *ImageUploadHandler) DeleteUserImage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	
@@ -364,31 +704,75 @@ func (h *ImageUploadHandler) DeleteUserImage(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	
-	// Validate user access to the image (assuming imageId is the key)
-	if !h.s3Service.ValidateUserAccess(userID, imageId) {
+	// Validate user access to the image
+	var img *catalog.Image
+	if h.s3Service.ValidateUserAccess(userID, imageId) {
+		img, err = h.catalog.FindByKey(ctx, userID, imageId)
+	} else if strings.HasPrefix(imageId, "users/") {
 		h.logger.Warn("User attempted to access unauthorized image", 
 			zap.String("userID", userID),
 			zap.String("imageId", imageId),
 		)
 		h.writeErrorResponse(w, http.StatusForbidden, "Access denied")
 		return
+	} else {
+		img, err = h.catalog.Get(ctx, userID, imageId)
+		if errors.Is(err, catalog.ErrNotFound) {
+			h.writeErrorResponse(w, http.StatusNotFound, "Image not found")
+			return
+		}
+	}
+	if err != nil && !errors.Is(err, catalog.ErrNotFound) {
+		h.logger.Error("Failed to look up image in catalog", zap.Error(err))
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete image")
+		return
 	}
 	
-	// Delete image from S3
-	err = h.s3Service.DeleteImage(ctx, imageId)
+	// The catalog entry is only removed once its objects are gone. S3
+	// deletes are idempotent, so a failed delete can simply be retried.
+	deleted := []string{imageId}
+	switch {
+	case img == nil:
+		// Not catalogued (yet); the object alone is deleted
+		err = h.s3Service.DeleteImage(ctx, imageId)
+	case imageId == img.EnhancedKey:
+		err = h.s3Service.DeleteImage(ctx, imageId)
+		if err == nil {
+			_, err = h.catalog.Update(ctx, userID, img.ID, func(img *catalog.Image) error {
+				if img.EnhancedKey != imageId {
+					return nil
+				}
+				img.EnhancedKey = ""
+				if img.Status == catalog.StatusEnhanced {
+					img.Status = catalog.StatusUploaded
+				}
+				return nil
+			})
+		}
+	default:
+		deleted, err = h.deleteCatalogued(ctx, userID, img.ID)
+	}
+	if errors.Is(err, catalog.ErrNotFound) {
+		// Deleted concurrently
+		h.writeErrorResponse(w, http.StatusNotFound, "Image not found")
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to delete image from S3", zap.Error(err))
+		h.logger.Error("Failed to delete image", zap.Error(err))
+		if writeCircuitOpen(w, err) {
+			return
+		}
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete image")
 		return
 	}
 	
-	// Invalidate CloudFront cache
-	go func() {
-		_, err := h.cloudFront.InvalidateCache(context.Background(), []string{imageId})
+	// Invalidate CloudFront cache in the background; shutdown waits for it
+	h.lifecycle.Go("cloudfront-invalidation", func(ctx context.Context) {
+		_, err := h.cloudFront.InvalidateCache(ctx, deleted)
 		if err != nil {
 			h.logger.Error("Failed to invalidate CloudFront cache", zap.Error(err))
 		}
-	}()
+	})
 	
 	response := map[string]interface{}{
 		"success": true,
@@ -398,8 +782,153 @@ func (h *ImageUploadHandler) DeleteUserImage(w http.ResponseWriter, r *http.Requ
 	h.writeJSONResponse(w, http.StatusOK, response)
 }
 
+// GetJob reports the state of an enhancement job; a succeeded job's result
+// holds the enhanced image URLs
+func (h *ImageUploadHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.extractUserIDFromToken(r)
+	if err != nil {
+		h.logger.Error("Failed to extract user ID from token", zap.Error(err))
+		h.writeErrorResponse(w, http.StatusUnauthorized, "Unauthorized: Invalid token")
+		return
+	}
+	
+	job, err := h.jobs.Get(mux.Vars(r)["id"], userID)
+	if err != nil {
+		h.writeErrorResponse(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	
+	// Job state changes until it finishes
+	w.Header().Set("Cache-Control", "no-store")
+	h.writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"job":     job,
+	})
+}
+
 // Helper methods
 
+// listingETag derives a strong ETag from the IDs and update times of the
+// catalog entries in a listing page and the total it reports
+func listingETag(images []*catalog.Image, total int) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n", total)
+	for _, img := range images {
+		io.WriteString(h, img.ID+"\x00"+img.UpdatedAt.UTC().Format(time.RFC3339Nano)+"\n")
+	}
+	return strongETag(h.Sum(nil))
+}
+
+// parseListOptions reads the paging, filter and sort parameters of an image
+// listing. Errors are suitable for the client.
+func parseListOptions(r *http.Request) (catalog.ListOptions, error) {
+	query := r.URL.Query()
+	opts := catalog.ListOptions{
+		Limit:   defaultPageSize,
+		Cursor:  query.Get("cursor"),
+		Product: strings.TrimSpace(query.Get("product")),
+	}
+	
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 {
+			return opts, errors.New("limit must be a positive number")
+		}
+		opts.Limit = min(limit, maxPageSize)
+	}
+	
+	switch imageType := query.Get("type"); imageType {
+	case "", "original", "enhanced":
+		opts.Type = imageType
+	default:
+		return opts, errors.New("type must be original or enhanced")
+	}
+	
+	var err error
+	if opts.From, err = parseListTime(query.Get("from"), false); err != nil {
+		return opts, errors.New("from must be a date (YYYY-MM-DD) or RFC 3339 time")
+	}
+	if opts.To, err = parseListTime(query.Get("to"), true); err != nil {
+		return opts, errors.New("to must be a date (YYYY-MM-DD) or RFC 3339 time")
+	}
+	if !opts.From.IsZero() && !opts.To.IsZero() && !opts.From.Before(opts.To) {
+		return opts, errors.New("from must be before to")
+	}
+	
+	if sort := query.Get("sort"); sort != "" && sort != "uploadedAt" {
+		return opts, errors.New("sort must be uploadedAt")
+	}
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		opts.Ascending = true
+	default:
+		return opts, errors.New("order must be asc or desc")
+	}
+	
+	return opts, nil
+}
+
+// parseListTime parses a date or RFC 3339 time. A date as the end of a range
+// includes that whole day.
+func parseListTime(value string, end bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// catalogImage converts a catalog entry for API responses
+func (h *ImageUploadHandler) catalogImage(img *catalog.Image) CatalogImage {
+	out := CatalogImage{
+		ID:          img.ID,
+		Key:         img.OriginalKey,
+		EnhancedKey: img.EnhancedKey,
+		ContentType: img.ContentType,
+		Size:        img.Size,
+		Width:       img.Width,
+		Height:      img.Height,
+		ProductName: img.ProductName,
+		Status:      img.Status,
+		JobID:       img.JobID,
+		CreatedAt:   img.CreatedAt,
+		UpdatedAt:   img.UpdatedAt,
+	}
+	if img.OriginalKey != "" {
+		out.URL = h.cloudFront.GetImageURL(img.OriginalKey)
+	}
+	if img.EnhancedKey != "" {
+		out.EnhancedURL = h.cloudFront.GetImageURL(img.EnhancedKey)
+	}
+	if len(img.VariantKeys) > 0 {
+		out.Variants = make(map[string]string, len(img.VariantKeys))
+		for name, key := range img.VariantKeys {
+			out.Variants[name] = h.cloudFront.GetImageURL(key)
+		}
+	}
+	return out
+}
+
+// imageDimensions reads the width and height from an image's header. The
+// caller rewinds the file afterwards.
+func imageDimensions(file io.Reader) (int, int) {
+	config, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return 0, 0
+	}
+	return config.Width, config.Height
+}
+
 func (h *ImageUploadHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"stox-gateway/internal/jobs"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// JobEventsHandler streams job events to clients as Server-Sent Events
+type JobEventsHandler struct {
+	broker    *jobs.Broker
+	heartbeat time.Duration
+	logger    *zap.Logger
+}
+
+// defaultHeartbeat is used when the configured heartbeat is not positive
+const defaultHeartbeat = 15 * time.Second
+
+// NewJobEventsHandler creates a handler that relays events from broker,
+// writing a heartbeat comment whenever a stream has been idle for heartbeat
+func NewJobEventsHandler(broker *jobs.Broker, heartbeat time.Duration, logger *zap.Logger) *JobEventsHandler {
+	if heartbeat <= 0 {
+		heartbeat = defaultHeartbeat
+	}
+	return &JobEventsHandler{broker: broker, heartbeat: heartbeat, logger: logger}
+}
+
+// lastEventID reads the resume point from the Last-Event-ID header, which
+// SSE clients send on reconnect, or the lastEventId query parameter
+func lastEventID(r *http.Request) int64 {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("lastEventId")
+	}
+	id, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || id < 0 {
+		return 0
+	}
+	return id
+}
+
+// Events serves GET /api/v1/images/jobs/{id}/events. Events already sent
+// before Last-Event-ID are skipped. The stream ends after the job's final
+// event; reconnecting to a finished job gets 204, which tells the client to
+// stop. Like every image route it requires an Authorization: Bearer header,
+// which the browser's EventSource cannot send, so browsers need a fetch-based
+// SSE client that sets the header and honours Last-Event-ID and 204.
+func (h *JobEventsHandler) Events(w http.ResponseWriter, r *http.Request) {
+	userID, _ := r.Context().Value(UserIDKey()).(string)
+	jobID := mux.Vars(r)["id"]
+
+	sub, err := h.broker.Subscribe(jobID, userID, lastEventID(r))
+	if errors.Is(err, jobs.ErrNotFound) {
+		h.writeErrorResponse(w, http.StatusNotFound, "Job not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to subscribe to job events", zap.String("jobID", jobID), zap.Error(err))
+		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to subscribe to job events")
+		return
+	}
+	defer sub.Cancel()
+
+	if sub.Ended && len(sub.Replay) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	// Stop nginx and similar proxies from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	send := func(write func() error) bool {
+		if err := write(); err != nil {
+			return false
+		}
+		if err := rc.Flush(); err != nil {
+			h.logger.Warn("Failed to flush job event stream", zap.Error(err))
+			return false
+		}
+		return true
+	}
+	sendEvent := func(event jobs.Event) bool {
+		return send(func() error {
+			_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+			return err
+		})
+	}
+
+	for _, event := range sub.Replay {
+		if !sendEvent(event) {
+			return
+		}
+	}
+	if sub.Ended {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Updates:
+			if !ok {
+				return
+			}
+			if !sendEvent(event) {
+				return
+			}
+			heartbeat.Reset(h.heartbeat)
+		case <-heartbeat.C:
+			if !send(func() error {
+				_, err := fmt.Fprint(w, ": heartbeat\n\n")
+				return err
+			}) {
+				return
+			}
+		}
+	}
+}
+
+// Helper methods
+
+func (h *JobEventsHandler) writeJSONResponse(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		h.logger.Error("Failed to encode JSON response", zap.Error(err))
+	}
+}
+
+func (h *JobEventsHandler) writeErrorResponse(w http.ResponseWriter, statusCode int, message string) {
+	response := map[string]interface{}{
+		"success": false,
+		"error":   message,
+	}
+	h.writeJSONResponse(w, statusCode, response)
+}
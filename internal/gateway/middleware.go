@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +21,7 @@ type contextKey string
 const (
 	requestIDKey contextKey = "request_id"
 	userIDKey    contextKey = "user_id"
+	userRoleKey  contextKey = "user_role"
 )
 
 // UserIDKey returns the context key for user ID (exported for use in handlers)
@@ -27,6 +29,11 @@ func UserIDKey() contextKey {
 	return userIDKey
 }
 
+// UserRoleKey returns the context key for the user's role (exported for use in handlers)
+func UserRoleKey() contextKey {
+	return userRoleKey
+}
+
 // generateRequestID creates a random request ID
 func generateRequestID() string {
 	bytes := make([]byte, 8)
@@ -65,6 +72,7 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 		// Log request start
 		logger.Debug("HTTP Request Started",
 			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("client_ip", clientIPFromContext(ctx)),
 			zap.String("user_agent", r.UserAgent()),
 		)
 
@@ -72,39 +80,122 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 
 		// Log request completion
 		logger.Info("HTTP Request Completed",
+			zap.String("client_ip", clientIPFromContext(ctx)),
 			zap.Int("status_code", wrapped.statusCode),
 			zap.Duration("duration", time.Since(start)),
 		)
 	})
 }
 
-// CORSMiddleware handles CORS headers with configurable allowed origins
+// originMatcher matches request origins against the configured allow list
+type originMatcher struct {
+	wildcard bool
+	exact    map[string]bool
+	patterns []originPattern
+}
+
+// originPattern is a subdomain pattern such as https://*.stox.app
+type originPattern struct {
+	scheme string
+	suffix string // host suffix including the leading dot, e.g. ".stox.app"
+}
+
+// newOriginMatcher compiles the allowed origins into exact matches,
+// subdomain patterns and the "*" wildcard
+func newOriginMatcher(allowedOrigins []string) *originMatcher {
+	m := &originMatcher{exact: make(map[string]bool)}
+	for _, origin := range allowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		switch {
+		case origin == "":
+			continue
+		case origin == "*":
+			m.wildcard = true
+		case strings.Contains(origin, "://*."):
+			scheme, host, _ := strings.Cut(origin, "://*")
+			m.patterns = append(m.patterns, originPattern{scheme: scheme, suffix: host})
+		default:
+			m.exact[origin] = true
+		}
+	}
+	return m
+}
+
+// match reports whether origin is allowed and whether it was only allowed
+// through the "*" wildcard
+func (m *originMatcher) match(origin string) (allowed bool, viaWildcard bool) {
+	if origin == "" {
+		return false, false
+	}
+	origin = strings.ToLower(origin)
+	if m.exact[origin] {
+		return true, false
+	}
+	for _, p := range m.patterns {
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok || scheme != p.scheme {
+			continue
+		}
+		// Require at least one label in front of the suffix so that
+		// https://*.stox.app does not match https://stox.app itself
+		if strings.HasSuffix(host, p.suffix) && len(host) > len(p.suffix) {
+			return true, false
+		}
+	}
+	if m.wildcard {
+		return true, true
+	}
+	return false, false
+}
+
+// CORSMiddleware handles CORS headers with configurable allowed origins.
+// Preflight requests are answered with 204 and never reach the router.
 func CORSMiddleware(corsConfig *config.CORSConfig) func(http.Handler) http.Handler {
+	matcher := newOriginMatcher(corsConfig.AllowedOrigins)
+	allowedMethods := strings.Join(corsConfig.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(corsConfig.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(corsConfig.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(corsConfig.MaxAge.Seconds()))
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
 
-			// Check if origin is in the allowed list
-			allowedOrigin := ""
-			for _, allowedOrig := range corsConfig.AllowedOrigins {
-				if origin == allowedOrig {
-					allowedOrigin = origin
-					break
-				}
+			// Responses differ by origin, so shared caches must key on it
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
 			}
 
 			// Set CORS headers only if origin is allowed
-			if allowedOrigin != "" {
-				w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
-				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			allowed, viaWildcard := matcher.match(origin)
+			if allowed {
+				if viaWildcard {
+					// Browsers reject "*" together with credentials, so the
+					// wildcard is only ever used for anonymous requests
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+				} else {
+					w.Header().Set("Access-Control-Allow-Origin", origin)
+					if corsConfig.AllowCredentials {
+						w.Header().Set("Access-Control-Allow-Credentials", "true")
+					}
+				}
+				if exposedHeaders != "" && !preflight {
+					w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
+				}
 			}
 
-			// Set other CORS headers
-			w.Header().Set("Access-Control-Allow-Methods", strings.Join(corsConfig.AllowedMethods, ", "))
-			w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsConfig.AllowedHeaders, ", "))
-
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
+			if preflight {
+				if allowed {
+					w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+					w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+					if corsConfig.MaxAge > 0 {
+						w.Header().Set("Access-Control-Max-Age", maxAge)
+					}
+				}
+				w.WriteHeader(http.StatusNoContent)
 				return
 			}
 
@@ -124,6 +215,11 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // AuthMiddleware validates JWT tokens and adds user context
 func AuthMiddleware(authClient interface {
 	ValidateToken(ctx context.Context, token string) (*pb.ValidateTokenResponse, error)
@@ -149,6 +245,9 @@ func AuthMiddleware(authClient interface {
 			// Validate token
 			validateResponse, err := authClient.ValidateToken(r.Context(), token)
 			if err != nil {
+				if writeCircuitOpen(w, err) {
+					return
+				}
 				http.Error(w, `{"success": false, "error": "Token validation failed"}`, http.StatusUnauthorized)
 				return
 			}
@@ -158,8 +257,9 @@ func AuthMiddleware(authClient interface {
 				return
 			}
 			
-			// Add user ID to request context
+			// Add user ID and role to request context
 			ctx := context.WithValue(r.Context(), userIDKey, validateResponse.UserId)
+			ctx = context.WithValue(ctx, userRoleKey, validateResponse.Role)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -0,0 +1,180 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"stox-gateway/internal/config"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// UserIDHeader carries the authenticated user's ID to proxied upstreams.
+// Any client-supplied value is always removed first.
+const UserIDHeader = "X-User-ID"
+
+// proxyRoute is a configured route bound to its reverse proxy
+type proxyRoute struct {
+	config.ProxyRoute
+	proxy *httputil.ReverseProxy
+}
+
+// ProxyRoutes serves configured reverse-proxy routes to HTTP upstreams
+type ProxyRoutes struct {
+	routes []*proxyRoute
+	logger *zap.Logger
+}
+
+// NewProxyRoutes builds a reverse proxy for every configured route
+func NewProxyRoutes(cfg *config.ProxyConfig, logger *zap.Logger) (*ProxyRoutes, error) {
+	p := &ProxyRoutes{logger: logger}
+
+	for _, routeCfg := range cfg.Routes {
+		if routeCfg.PathPrefix == "" {
+			return nil, fmt.Errorf("proxy route to %s has no path_prefix", routeCfg.Upstream)
+		}
+		upstream, err := url.Parse(routeCfg.Upstream)
+		if err != nil || upstream.Scheme == "" || upstream.Host == "" {
+			return nil, fmt.Errorf("proxy route %s has an invalid upstream %q", routeCfg.PathPrefix, routeCfg.Upstream)
+		}
+
+		route := &proxyRoute{ProxyRoute: routeCfg}
+		route.proxy = &httputil.ReverseProxy{
+			Rewrite:        func(pr *httputil.ProxyRequest) { route.rewrite(pr, upstream) },
+			ModifyResponse: route.modifyResponse,
+			ErrorHandler:   p.errorHandler(route),
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   10 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				MaxIdleConnsPerHost:   32,
+				IdleConnTimeout:       90 * time.Second,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: routeCfg.Timeout,
+			},
+		}
+		p.routes = append(p.routes, route)
+
+		logger.Info("Registered proxy route",
+			zap.String("pathPrefix", routeCfg.PathPrefix),
+			zap.String("upstream", routeCfg.Upstream),
+		)
+	}
+
+	return p, nil
+}
+
+// rewrite maps the inbound request onto the upstream, replacing the route
+// prefix and applying the configured request header changes
+func (route *proxyRoute) rewrite(pr *httputil.ProxyRequest, upstream *url.URL) {
+	pr.SetURL(upstream)
+	pr.SetXForwarded()
+	// SetXForwarded names the immediate peer, which behind a load balancer
+	// is the balancer; forward the client IP resolved from trusted proxies
+	if clientIP := clientIPFromContext(pr.In.Context()); clientIP != "" {
+		pr.Out.Header.Set("X-Forwarded-For", clientIP)
+	}
+
+	// SetURL joins the upstream path with the full inbound path, so rebuild
+	// it from the part after the route prefix
+	rest := strings.TrimPrefix(pr.In.URL.Path, strings.TrimSuffix(route.PathPrefix, "/"))
+	rewritten := path.Join("/", upstream.Path, route.RewritePrefix, rest)
+	if strings.HasSuffix(pr.In.URL.Path, "/") && !strings.HasSuffix(rewritten, "/") {
+		rewritten += "/"
+	}
+	pr.Out.URL.Path = rewritten
+	pr.Out.URL.RawPath = ""
+
+	pr.Out.Header.Del(UserIDHeader)
+	if userID, ok := pr.In.Context().Value(userIDKey).(string); ok && userID != "" {
+		pr.Out.Header.Set(UserIDHeader, userID)
+	}
+	if requestID, ok := pr.In.Context().Value(requestIDKey).(string); ok {
+		pr.Out.Header.Set("X-Request-ID", requestID)
+	}
+
+	for _, name := range route.RequestHeaders.Remove {
+		pr.Out.Header.Del(name)
+	}
+	for name, value := range route.RequestHeaders.Add {
+		pr.Out.Header.Set(name, value)
+	}
+}
+
+// modifyResponse applies the configured response header changes
+func (route *proxyRoute) modifyResponse(resp *http.Response) error {
+	for _, name := range route.ResponseHeaders.Remove {
+		resp.Header.Del(name)
+	}
+	for name, value := range route.ResponseHeaders.Add {
+		resp.Header.Set(name, value)
+	}
+	return nil
+}
+
+// errorHandler answers upstream failures in the gateway's error shape
+func (p *ProxyRoutes) errorHandler(route *proxyRoute) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		statusCode := http.StatusBadGateway
+		message := "Upstream service unavailable"
+		if errors.Is(err, context.DeadlineExceeded) {
+			statusCode = http.StatusGatewayTimeout
+			message = "Upstream service timed out"
+		} else if errors.Is(err, context.Canceled) {
+			// The client went away; nobody will read the response
+			return
+		}
+
+		p.logger.Error("Proxy request failed",
+			zap.String("upstream", route.Upstream),
+			zap.String("path", r.URL.Path),
+			zap.Error(err),
+		)
+		http.Error(w, fmt.Sprintf(`{"success": false, "error": "%s"}`, message), statusCode)
+	}
+}
+
+// Register adds the proxy routes to the router. Routes with auth enabled are
+// wrapped with authMiddleware.
+func (p *ProxyRoutes) Register(router *mux.Router, authMiddleware func(http.Handler) http.Handler) {
+	for _, route := range p.routes {
+		var handler http.Handler = route.handler()
+		if route.Auth {
+			handler = authMiddleware(handler)
+		}
+		router.MatcherFunc(prefixMatcher(route.PathPrefix)).Handler(handler)
+	}
+}
+
+// prefixMatcher matches the prefix itself and paths below it, unlike
+// mux's PathPrefix, which also matches /api/v1/auth-httpfoo for the prefix
+// /api/v1/auth-http
+func prefixMatcher(prefix string) mux.MatcherFunc {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(r *http.Request, _ *mux.RouteMatch) bool {
+		return r.URL.Path == prefix || strings.HasPrefix(r.URL.Path, prefix+"/")
+	}
+}
+
+// handler applies the per-upstream timeout before proxying
+func (route *proxyRoute) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(r.Context(), route.Timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+		}
+		route.proxy.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+
+	"stox-gateway/internal/config"
+)
+
+func TestProxyRoutes(t *testing.T) {
+	var gotPath, gotXFF string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotXFF = r.URL.Path, r.Header.Get("X-Forwarded-For")
+	}))
+	defer upstream.Close()
+
+	proxies, err := NewProxyRoutes(&config.ProxyConfig{Routes: []config.ProxyRoute{
+		{PathPrefix: "/api/v1/auth-http", Upstream: upstream.URL, RewritePrefix: "/auth"},
+	}}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewProxyRoutes: %v", err)
+	}
+	router := mux.NewRouter()
+	proxies.Register(router, nil)
+
+	tests := []struct {
+		name     string
+		path     string
+		clientIP string
+		xff      string
+		wantCode int
+		wantPath string
+		wantXFF  string
+	}{
+		{
+			name:     "prefix itself",
+			path:     "/api/v1/auth-http",
+			wantCode: http.StatusOK,
+			wantPath: "/auth",
+		},
+		{
+			name:     "below the prefix",
+			path:     "/api/v1/auth-http/login",
+			wantCode: http.StatusOK,
+			wantPath: "/auth/login",
+		},
+		{
+			name:     "longer segment",
+			path:     "/api/v1/auth-httpfoo",
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "resolved client IP",
+			path:     "/api/v1/auth-http/login",
+			clientIP: "203.0.113.7",
+			xff:      "198.51.100.1, 203.0.113.7",
+			wantCode: http.StatusOK,
+			wantPath: "/auth/login",
+			wantXFF:  "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotPath, gotXFF = "", ""
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.clientIP != "" {
+				req = req.WithContext(context.WithValue(req.Context(), clientIPKey, tt.clientIP))
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+			if gotPath != tt.wantPath {
+				t.Errorf("upstream path = %q, want %q", gotPath, tt.wantPath)
+			}
+			if tt.wantXFF != "" && gotXFF != tt.wantXFF {
+				t.Errorf("X-Forwarded-For = %q, want %q", gotXFF, tt.wantXFF)
+			}
+		})
+	}
+}
@@ -3,12 +3,46 @@ package gateway
 import (
 	"log"
 	"net/http"
+	"time"
+
+	"stox-gateway/internal/features"
 
 	"github.com/gorilla/mux"
 )
 
+// RouterOptions holds optional handlers and route-level middleware that are
+// configured outside the gateway package. Nil fields are skipped.
+type RouterOptions struct {
+	// Idempotency wraps POST routes that clients may safely retry
+	Idempotency func(http.Handler) http.Handler
+
+	// Flags gates individual routes; FeatureRetryAfter is sent with the 503
+	// returned while a gated route is switched off
+	Flags             *features.Flags
+	FeatureRetryAfter time.Duration
+
+	// Admin serves the /admin endpoints, restricted to the admin role
+	Admin *AdminHandler
+
+	// Transcoder serves the config-driven HTTP-to-gRPC routes
+	Transcoder *Transcoder
+
+	// Proxy serves the reverse-proxy routes to HTTP upstreams
+	Proxy *ProxyRoutes
+
+	// Ready reports whether the gateway should receive traffic; /health fails
+	// once it returns false so load balancers drain the instance
+	Ready func() bool
+
+	// Health serves /livez and /readyz
+	Health *HealthHandler
+
+	// JobEvents streams image job progress as Server-Sent Events
+	JobEvents *JobEventsHandler
+}
+
 // Router sets up the HTTP routes
-func NewRouter(authHandler *AuthHandler, imageHandler *ImageHandler, imageUploadHandler *ImageUploadHandler) *mux.Router {
+func NewRouter(authHandler *AuthHandler, imageHandler *ImageHandler, imageUploadHandler *ImageUploadHandler, opts RouterOptions) *mux.Router {
 	// Check for nil handlers to prevent runtime panics
 	if authHandler == nil {
 		log.Printf("NewRouter: authHandler parameter is nil, cannot set up auth routes")
@@ -23,6 +57,11 @@ func NewRouter(authHandler *AuthHandler, imageHandler *ImageHandler, imageUpload
 		return nil
 	}
 
+	idempotency := opts.Idempotency
+	if idempotency == nil {
+		idempotency = func(next http.Handler) http.Handler { return next }
+	}
+
 	router := mux.NewRouter()
 
 	// API versioning
@@ -30,7 +69,7 @@ func NewRouter(authHandler *AuthHandler, imageHandler *ImageHandler, imageUpload
 
 	// Auth routes
 	auth := api.PathPrefix("/auth").Subrouter()
-	auth.HandleFunc("/register", authHandler.Register).Methods("POST")
+	auth.Handle("/register", idempotency(http.HandlerFunc(authHandler.Register))).Methods("POST")
 	auth.HandleFunc("/login", authHandler.Login).Methods("POST")
 	auth.HandleFunc("/validate", authHandler.ValidateToken).Methods("POST")
 	auth.HandleFunc("/profile", authHandler.GetProfile).Methods("GET")
@@ -43,12 +82,56 @@ func NewRouter(authHandler *AuthHandler, imageHandler *ImageHandler, imageUpload
 	images := api.PathPrefix("/images").Subrouter()
 	// Add authentication middleware for all image operations
 	images.Use(AuthMiddleware(authHandler.GetAuthClient()))
-	images.HandleFunc("/upload", imageUploadHandler.UploadImage).Methods("POST")
+	// Idempotency keys are scoped per user, so this must run after auth
+	images.Use(idempotency)
+	uploadsGate := RequireFeature(opts.Flags, features.Uploads, int(opts.FeatureRetryAfter.Seconds()))
+	images.Handle("/upload", uploadsGate(http.HandlerFunc(imageUploadHandler.UploadImage))).Methods("POST")
 	images.HandleFunc("/list", imageUploadHandler.GetUserImages).Methods("GET")
 	images.HandleFunc("/delete/{imageId}", imageUploadHandler.DeleteUserImage).Methods("DELETE")
+	images.HandleFunc("/jobs/{id}", imageUploadHandler.GetJob).Methods("GET")
+	if opts.JobEvents != nil {
+		images.HandleFunc("/jobs/{id}/events", opts.JobEvents.Events).Methods("GET")
+	}
+
+	// Config-driven HTTP-to-gRPC routes, registered after the hand-written
+	// routes so that those always take precedence
+	if opts.Transcoder != nil {
+		opts.Transcoder.Register(router, AuthMiddleware(authHandler.GetAuthClient()))
+	}
+
+	// Reverse-proxy routes to HTTP upstreams
+	if opts.Proxy != nil {
+		opts.Proxy.Register(router, AuthMiddleware(authHandler.GetAuthClient()))
+	}
+
+	// Admin routes
+	if opts.Admin != nil {
+		admin := router.PathPrefix("/admin").Subrouter()
+		admin.Use(AuthMiddleware(authHandler.GetAuthClient()))
+		admin.Use(RequireRole("admin"))
+		admin.HandleFunc("/flags", opts.Admin.ListFlags).Methods("GET")
+		admin.HandleFunc("/flags/{name}", opts.Admin.SetFlag).Methods("PUT")
+		admin.HandleFunc("/flags/{name}", opts.Admin.ResetFlag).Methods("DELETE")
+		admin.HandleFunc("/shadow", opts.Admin.ShadowStats).Methods("GET")
+	}
+
+	// Kubernetes probes
+	if opts.Health != nil {
+		router.HandleFunc("/livez", opts.Health.Livez).Methods("GET")
+		router.HandleFunc("/readyz", opts.Health.Readyz).Methods("GET")
+	}
 
 	// Health check
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		// Fail while shutting down so no new traffic is routed here
+		if opts.Ready != nil && !opts.Ready() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			if _, err := w.Write([]byte("Shutting down")); err != nil {
+				log.Printf("Health check: failed to write response body: %v", err)
+			}
+			return
+		}
+
 		// Set status header - WriteHeader doesn't return an error but can fail silently
 		// if called after writing has begun, so we call it first
 		w.WriteHeader(http.StatusOK)
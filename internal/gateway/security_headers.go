@@ -0,0 +1,134 @@
+package gateway
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"stox-gateway/internal/config"
+)
+
+const (
+	// defaultHSTSMaxAge is used outside development when hsts_max_age is unset
+	defaultHSTSMaxAge = 365 * 24 * time.Hour
+
+	// defaultContentSecurityPolicy locks down JSON API responses, which never
+	// need to load sub-resources or be framed
+	defaultContentSecurityPolicy = "default-src 'none'; frame-ancestors 'none'"
+)
+
+// securityHeadersRoute holds the resolved header set for a route prefix
+type securityHeadersRoute struct {
+	pathPrefix string
+	headers    http.Header
+}
+
+// isDevelopment reports whether the server environment is a local one
+func isDevelopment(environment string) bool {
+	switch strings.ToLower(environment) {
+	case "", "development", "dev", "local", "test":
+		return true
+	}
+	return false
+}
+
+// defaultSecurityHeaders resolves the base header set from config, falling back
+// to defaults for the given environment. HSTS is only sent outside development
+// so that browsers don't pin localhost to HTTPS.
+func defaultSecurityHeaders(cfg *config.SecurityHeadersConfig, environment string) http.Header {
+	headers := http.Header{}
+	dev := isDevelopment(environment)
+
+	headers.Set("X-Content-Type-Options", valueOrDefault(cfg.ContentTypeOptions, "nosniff"))
+	headers.Set("X-Frame-Options", valueOrDefault(cfg.FrameOptions, "DENY"))
+	headers.Set("Content-Security-Policy", valueOrDefault(cfg.ContentSecurityPolicy, defaultContentSecurityPolicy))
+
+	if dev {
+		headers.Set("Referrer-Policy", valueOrDefault(cfg.ReferrerPolicy, "strict-origin-when-cross-origin"))
+	} else {
+		headers.Set("Referrer-Policy", valueOrDefault(cfg.ReferrerPolicy, "no-referrer"))
+	}
+
+	hstsMaxAge := cfg.HSTSMaxAge
+	if hstsMaxAge == 0 && !dev {
+		hstsMaxAge = defaultHSTSMaxAge
+	}
+	if hstsMaxAge > 0 {
+		hsts := fmt.Sprintf("max-age=%d", int64(hstsMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		headers.Set("Strict-Transport-Security", hsts)
+	}
+
+	return headers
+}
+
+// valueOrDefault returns value unless it is empty
+func valueOrDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// resolveSecurityHeadersRoutes merges each route override onto the base header
+// set and orders the result by prefix length so the most specific route wins
+func resolveSecurityHeadersRoutes(base http.Header, routes []config.SecurityHeadersRoute) []securityHeadersRoute {
+	resolved := make([]securityHeadersRoute, 0, len(routes))
+	for _, route := range routes {
+		if route.PathPrefix == "" {
+			continue
+		}
+		headers := base.Clone()
+		for name, value := range route.Headers {
+			if value == "" {
+				headers.Del(name)
+				continue
+			}
+			headers.Set(name, value)
+		}
+		resolved = append(resolved, securityHeadersRoute{pathPrefix: route.PathPrefix, headers: headers})
+	}
+
+	sort.SliceStable(resolved, func(i, j int) bool {
+		return len(resolved[i].pathPrefix) > len(resolved[j].pathPrefix)
+	})
+	return resolved
+}
+
+// SecurityHeadersMiddleware sets HSTS, X-Content-Type-Options, X-Frame-Options,
+// Referrer-Policy and Content-Security-Policy on every response, with
+// per-route overrides taken from the security_headers config section
+func SecurityHeadersMiddleware(cfg *config.SecurityHeadersConfig, environment string) func(http.Handler) http.Handler {
+	if !cfg.Enabled {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	base := defaultSecurityHeaders(cfg, environment)
+	routes := resolveSecurityHeadersRoutes(base, cfg.Routes)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			headers := base
+			for _, route := range routes {
+				if strings.HasPrefix(r.URL.Path, route.pathPrefix) {
+					headers = route.headers
+					break
+				}
+			}
+
+			// Set headers before the handler runs so they are in place even
+			// when the handler writes the response body straight away
+			for name, values := range headers {
+				w.Header()[name] = append([]string(nil), values...)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
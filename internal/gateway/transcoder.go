@@ -0,0 +1,440 @@
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"stox-gateway/internal/config"
+	"stox-gateway/internal/grpcclients"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// transcodingRoute is a configured route bound to its resolved gRPC method
+type transcodingRoute struct {
+	config.TranscodingRoute
+	fullMethod string // "/package.Service/Method"
+	method     protoreflect.MethodDescriptor
+	client     *grpcclients.DynamicClient
+}
+
+// Transcoder serves config-driven HTTP routes by binding path, query and JSON
+// body fields onto dynamic request messages and invoking the mapped gRPC
+// method. Responses are marshaled with protojson.
+type Transcoder struct {
+	routes      []*transcodingRoute
+	clients     map[string]*grpcclients.DynamicClient
+	maxBodySize int64
+	logger      *zap.Logger
+}
+
+// defaultMaxBodySize caps request bodies when no max_body_size is configured
+const defaultMaxBodySize = 1024 * 1024
+
+// NewTranscoder resolves every configured route against the descriptor sets
+// and the compiled-in protos, and connects to the backends they target
+func NewTranscoder(cfg *config.TranscodingConfig, services *config.ServicesConfig, logger *zap.Logger) (*Transcoder, error) {
+	files, err := loadDescriptorSets(cfg.DescriptorSets)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transcoder{
+		clients:     make(map[string]*grpcclients.DynamicClient),
+		maxBodySize: cfg.MaxBodySize,
+		logger:      logger,
+	}
+	if t.maxBodySize <= 0 {
+		t.maxBodySize = defaultMaxBodySize
+	}
+
+	for _, routeCfg := range cfg.Routes {
+		method, err := findMethod(files, routeCfg.GRPCMethod)
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+		if method.IsStreamingClient() || method.IsStreamingServer() {
+			t.Close()
+			return nil, fmt.Errorf("transcoding route %s %s: streaming method %s is not supported", routeCfg.Method, routeCfg.Path, routeCfg.GRPCMethod)
+		}
+
+		client, err := t.client(routeCfg.Service, services)
+		if err != nil {
+			t.Close()
+			return nil, err
+		}
+
+		t.routes = append(t.routes, &transcodingRoute{
+			TranscodingRoute: routeCfg,
+			fullMethod:       fmt.Sprintf("/%s/%s", method.Parent().FullName(), method.Name()),
+			method:           method,
+			client:           client,
+		})
+
+		logger.Info("Registered transcoding route",
+			zap.String("method", routeCfg.Method),
+			zap.String("path", routeCfg.Path),
+			zap.String("grpcMethod", routeCfg.GRPCMethod),
+		)
+	}
+
+	return t, nil
+}
+
+// loadDescriptorSets reads FileDescriptorSet files into a registry
+func loadDescriptorSets(paths []string) (*protoregistry.Files, error) {
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read descriptor set %s: %w", path, err)
+		}
+		var fileSet descriptorpb.FileDescriptorSet
+		if err := proto.Unmarshal(data, &fileSet); err != nil {
+			return nil, fmt.Errorf("failed to parse descriptor set %s: %w", path, err)
+		}
+		set.File = append(set.File, fileSet.File...)
+	}
+
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build descriptors: %w", err)
+	}
+	return files, nil
+}
+
+// findMethod resolves "package.Service/Method" or "package.Service.Method",
+// preferring the loaded descriptor sets over the compiled-in protos
+func findMethod(files *protoregistry.Files, name string) (protoreflect.MethodDescriptor, error) {
+	name = strings.TrimPrefix(name, "/")
+	serviceName, methodName, ok := strings.Cut(name, "/")
+	if !ok {
+		idx := strings.LastIndex(name, ".")
+		if idx < 0 {
+			return nil, fmt.Errorf("invalid gRPC method name %q", name)
+		}
+		serviceName, methodName = name[:idx], name[idx+1:]
+	}
+
+	desc, err := files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		desc, err = protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(serviceName))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gRPC service %s not found in descriptors", serviceName)
+	}
+
+	service, ok := desc.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a gRPC service", serviceName)
+	}
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("gRPC method %s not found on service %s", methodName, serviceName)
+	}
+	return method, nil
+}
+
+// client returns the shared connection for a backend service
+func (t *Transcoder) client(name string, services *config.ServicesConfig) (*grpcclients.DynamicClient, error) {
+	if client, ok := t.clients[name]; ok {
+		return client, nil
+	}
+
+	serviceCfg, ok := services.ByName(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown service %q in transcoding route", name)
+	}
+
+	client, err := grpcclients.NewDynamicClient(name, serviceCfg, t.logger)
+	if err != nil {
+		return nil, err
+	}
+	t.clients[name] = client
+	return client, nil
+}
+
+// Close closes every backend connection
+func (t *Transcoder) Close() error {
+	var firstErr error
+	for _, client := range t.clients {
+		if err := client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Register adds the transcoding routes to the router. Routes with auth enabled
+// are wrapped with authMiddleware.
+func (t *Transcoder) Register(router *mux.Router, authMiddleware func(http.Handler) http.Handler) {
+	for _, route := range t.routes {
+		var handler http.Handler = t.handler(route)
+		if route.Auth {
+			handler = authMiddleware(handler)
+		}
+		router.Handle(route.Path, handler).Methods(route.Method)
+	}
+}
+
+// handler serves a single transcoding route
+func (t *Transcoder) handler(route *transcodingRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := dynamicpb.NewMessage(route.method.Input())
+
+		// Bodies are buffered before binding, so bound them first; some
+		// routes, such as the token refresh, need no authentication
+		var body []byte
+		if route.Body != "" {
+			var err error
+			body, err = io.ReadAll(io.LimitReader(r.Body, t.maxBodySize+1))
+			if err != nil {
+				writeValidationErrors(w, []ValidationError{{Field: "body", Message: "Failed to read request body"}})
+				return
+			}
+			if int64(len(body)) > t.maxBodySize {
+				http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+		}
+
+		if errs := t.bindRequest(r, route, req, body); len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		ctx := r.Context()
+		if route.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, route.Timeout)
+			defer cancel()
+		}
+		if requestID, ok := ctx.Value(requestIDKey).(string); ok {
+			ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", requestID)
+		}
+
+		resp := dynamicpb.NewMessage(route.method.Output())
+		if err := route.client.Invoke(ctx, route.fullMethod, req, resp); err != nil {
+			writeGRPCError(w, err)
+			return
+		}
+
+		body, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(resp)
+		if err != nil {
+			t.logger.Error("Failed to marshal transcoded response", zap.Error(err))
+			http.Error(w, "Internal server error: failed to encode response", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(body); err != nil {
+			t.logger.Error("Failed to write transcoded response", zap.Error(err))
+		}
+	}
+}
+
+// bindRequest fills the request message from the JSON body, then query
+// parameters, then path variables, so that the path always wins
+func (t *Transcoder) bindRequest(r *http.Request, route *transcodingRoute, req *dynamicpb.Message, body []byte) []ValidationError {
+	var errs []ValidationError
+
+	if route.Body != "" && len(strings.TrimSpace(string(body))) > 0 {
+		if err := bindBody(req, route.Body, body); err != nil {
+			return []ValidationError{{Field: "body", Message: err.Error()}}
+		}
+	}
+
+	for name, values := range r.URL.Query() {
+		// Unknown query parameters, e.g. cache busters, are ignored
+		if err := setField(req, name, values); err != nil && !errors.Is(err, errUnknownField) {
+			errs = append(errs, ValidationError{Field: name, Message: err.Error()})
+		}
+	}
+
+	for name, value := range mux.Vars(r) {
+		if err := setField(req, name, []string{value}); err != nil {
+			errs = append(errs, ValidationError{Field: name, Message: err.Error()})
+		}
+	}
+
+	// Bound last so that clients can never choose the user ID themselves
+	if route.UserIDField != "" {
+		userID, _ := r.Context().Value(userIDKey).(string)
+		if err := setField(req, route.UserIDField, []string{userID}); err != nil {
+			t.logger.Error("Failed to bind user ID field",
+				zap.String("field", route.UserIDField),
+				zap.Error(err),
+			)
+			errs = append(errs, ValidationError{Field: route.UserIDField, Message: err.Error()})
+		}
+	}
+
+	return errs
+}
+
+// bindBody unmarshals the JSON body into the whole message or into one of
+// its message fields
+func bindBody(req *dynamicpb.Message, target string, body []byte) error {
+	if target == "*" {
+		return protojson.Unmarshal(body, req)
+	}
+
+	field := req.Descriptor().Fields().ByName(protoreflect.Name(target))
+	if field == nil || field.Message() == nil || field.IsList() || field.IsMap() {
+		return fmt.Errorf("body field %q is not a message field", target)
+	}
+	sub := req.Mutable(field).Message().Interface()
+	return protojson.Unmarshal(body, sub)
+}
+
+// errUnknownField is returned by setField when the path names no field
+var errUnknownField = errors.New("unknown field")
+
+// setField assigns string values to the field at a dotted path such as
+// "filter.product_name". Fields may be named by proto or JSON name.
+func setField(msg protoreflect.Message, path string, values []string) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fields := msg.Descriptor().Fields()
+		field := fields.ByName(protoreflect.Name(name))
+		if field == nil {
+			field = fields.ByJSONName(name)
+		}
+		if field == nil {
+			return errUnknownField
+		}
+
+		if i < len(names)-1 {
+			if field.Message() == nil || field.IsList() || field.IsMap() {
+				return fmt.Errorf("%s is not a message field", name)
+			}
+			msg = msg.Mutable(field).Message()
+			continue
+		}
+
+		if field.IsMap() || (field.Message() != nil && !field.IsList()) {
+			return fmt.Errorf("%s cannot be set from a string", name)
+		}
+		if field.IsList() {
+			list := msg.Mutable(field).List()
+			for _, value := range values {
+				v, err := parseScalar(field, value)
+				if err != nil {
+					return err
+				}
+				list.Append(v)
+			}
+			return nil
+		}
+
+		v, err := parseScalar(field, values[len(values)-1])
+		if err != nil {
+			return err
+		}
+		msg.Set(field, v)
+	}
+	return nil
+}
+
+// parseScalar converts a string to a value of the field's kind
+func parseScalar(field protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	invalid := func(error) (protoreflect.Value, error) {
+		return protoreflect.Value{}, fmt.Errorf("invalid %s value %q", field.Kind(), value)
+	}
+
+	switch field.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BoolKind:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfBool(v), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		v, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfInt32(int32(v)), nil
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfInt64(v), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		v, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfUint32(uint32(v)), nil
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfUint64(v), nil
+	case protoreflect.FloatKind:
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfFloat32(float32(v)), nil
+	case protoreflect.DoubleKind:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfFloat64(v), nil
+	case protoreflect.BytesKind:
+		v, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			v, err = base64.URLEncoding.DecodeString(value)
+		}
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfBytes(v), nil
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByName(protoreflect.Name(value)); enumValue != nil {
+			return protoreflect.ValueOfEnum(enumValue.Number()), nil
+		}
+		v, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return invalid(err)
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(v)), nil
+	}
+	return protoreflect.Value{}, fmt.Errorf("unsupported field kind %s", field.Kind())
+}
+
+// writeValidationErrors writes a 400 with the gateway's validation error shape
+func writeValidationErrors(w http.ResponseWriter, errs []ValidationError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+
+	validationResponse := ValidationErrors{Errors: errs}
+	if err := json.NewEncoder(w).Encode(validationResponse); err != nil {
+		// If JSON encoding fails, fall back to plain text error
+		http.Error(w, "Internal server error: failed to encode validation errors", http.StatusInternalServerError)
+	}
+}
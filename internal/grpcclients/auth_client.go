@@ -3,13 +3,12 @@ package grpcclients
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/status"
 
+	"stox-gateway/internal/breaker"
+	"stox-gateway/internal/config"
 	pb "stox-gateway/internal/proto/auth"
 )
 
@@ -108,22 +107,51 @@ func safeLogUserProfileResponse(resp *pb.UserProfileResponse) map[string]interfa
 
 // AuthClient represents a gRPC client for the auth service
 type AuthClient struct {
-	client pb.AuthServiceClient
-	conn   *grpc.ClientConn
-	logger *zap.Logger
+	client     pb.AuthServiceClient
+	conn       *grpc.ClientConn
+	stopReload func()
+	logger     *zap.Logger
 }
 
 // NewAuthClient creates a new auth client
-func NewAuthClient(host string, port int, logger *zap.Logger) (*AuthClient, error) {
-	address := fmt.Sprintf("%s:%d", host, port)
+func NewAuthClient(cfg config.ServiceConfig, logger *zap.Logger) (*AuthClient, error) {
+	address := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
 
 	logger.Info("Connecting to auth service",
 		zap.String("address", address),
 	)
 
-	// Create insecure connection (use TLS in production)
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	creds, stopReload, err := transportCredentials("auth", cfg.TLS, logger)
 	if err != nil {
+		logger.Error("Failed to configure TLS for auth service", zap.Error(err))
+		return nil, fmt.Errorf("failed to configure TLS for auth service: %w", err)
+	}
+
+	retry, err := retryInterceptor("auth", cfg.RetryPolicies, logger)
+	if err != nil {
+		stopReload()
+		return nil, err
+	}
+
+	target, targetOpts, err := dialTarget("auth", cfg.Host, cfg.Port, cfg, logger)
+	if err != nil {
+		stopReload()
+		return nil, err
+	}
+
+	transportOpts, err := transportOptions("auth", cfg)
+	if err != nil {
+		stopReload()
+		return nil, err
+	}
+
+	circuit := breaker.New("auth", cfg.CircuitBreaker, logger)
+
+	opts := append(targetOpts, transportOpts...)
+	opts = append(opts, creds, grpc.WithChainUnaryInterceptor(timeoutInterceptor(cfg), breakerInterceptor(circuit), retry))
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		stopReload()
 		logger.Error("Failed to connect to auth service", zap.Error(err))
 		return nil, fmt.Errorf("failed to connect to auth service: %v", err)
 	}
@@ -133,15 +161,17 @@ func NewAuthClient(host string, port int, logger *zap.Logger) (*AuthClient, erro
 	client := pb.NewAuthServiceClient(conn)
 
 	return &AuthClient{
-		client: client,
-		conn:   conn,
-		logger: logger,
+		client:     client,
+		conn:       conn,
+		stopReload: stopReload,
+		logger:     logger,
 	}, nil
 }
 
 // Close closes the gRPC connection
 func (c *AuthClient) Close() error {
 	c.logger.Info("Closing connection to auth service")
+	c.stopReload()
 	return c.conn.Close()
 }
 
@@ -162,17 +192,10 @@ func (c *AuthClient) Register(ctx context.Context, email, password, firstName, l
 		Role:      role,
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
 	resp, err := c.client.Register(ctx, req)
 	if err != nil {
 		c.logger.Error("Register request failed", zap.Error(err))
-		st, ok := status.FromError(err)
-		if ok {
-			return nil, fmt.Errorf("register failed: %s", st.Message())
-		}
-		return nil, fmt.Errorf("register failed: %v", err)
+		return nil, callError("register", err)
 	}
 
 	c.logger.Debug("Register request successful", zap.Any("response", safeLogAuthResponse(resp)))
@@ -191,17 +214,10 @@ func (c *AuthClient) Login(ctx context.Context, email, password string) (*pb.Aut
 		zap.String("email", email),
 	)
 
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
 	resp, err := c.client.Login(ctx, req)
 	if err != nil {
 		c.logger.Error("Login request failed", zap.Error(err))
-		st, ok := status.FromError(err)
-		if ok {
-			return nil, fmt.Errorf("login failed: %s", st.Message())
-		}
-		return nil, fmt.Errorf("login failed: %v", err)
+		return nil, callError("login", err)
 	}
 
 	c.logger.Debug("Login request successful", zap.Any("response", safeLogAuthResponse(resp)))
@@ -219,17 +235,10 @@ func (c *AuthClient) ValidateToken(ctx context.Context, token string) (*pb.Valid
 		zap.String("token", token[:min(len(token), 20)]+"..."), // truncate token for security
 	)
 
-	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
-	defer cancel()
-
 	resp, err := c.client.ValidateToken(ctx, req)
 	if err != nil {
 		c.logger.Error("Token validation request failed", zap.Error(err))
-		st, ok := status.FromError(err)
-		if ok {
-			return nil, fmt.Errorf("token validation failed: %s", st.Message())
-		}
-		return nil, fmt.Errorf("token validation failed: %v", err)
+		return nil, callError("token validation", err)
 	}
 
 	c.logger.Debug("Token validation request successful", zap.Any("response", safeLogValidateTokenResponse(resp)))
@@ -247,17 +256,10 @@ func (c *AuthClient) RefreshToken(ctx context.Context, refreshToken string) (*pb
 		zap.String("refreshToken", refreshToken[:min(len(refreshToken), 20)]+"..."), // truncate token for security
 	)
 
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
 	resp, err := c.client.RefreshToken(ctx, req)
 	if err != nil {
 		c.logger.Error("Token refresh request failed", zap.Error(err))
-		st, ok := status.FromError(err)
-		if ok {
-			return nil, fmt.Errorf("token refresh failed: %s", st.Message())
-		}
-		return nil, fmt.Errorf("token refresh failed: %v", err)
+		return nil, callError("token refresh", err)
 	}
 
 	c.logger.Debug("Token refresh request successful", zap.Any("response", safeLogAuthResponse(resp)))
@@ -275,17 +277,10 @@ func (c *AuthClient) GetProfile(ctx context.Context, userID string) (*pb.UserPro
 		zap.String("userID", userID),
 	)
 
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
 	resp, err := c.client.GetProfile(ctx, req)
 	if err != nil {
 		c.logger.Error("Get profile request failed", zap.Error(err))
-		st, ok := status.FromError(err)
-		if ok {
-			return nil, fmt.Errorf("get profile failed: %s", st.Message())
-		}
-		return nil, fmt.Errorf("get profile failed: %v", err)
+		return nil, callError("get profile", err)
 	}
 
 	c.logger.Debug("Get profile request successful", zap.Any("response", safeLogUserProfileResponse(resp)))
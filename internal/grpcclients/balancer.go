@@ -0,0 +1,261 @@
+package grpcclients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/serviceconfig"
+
+	"stox-gateway/internal/config"
+)
+
+// balancerName is the load-balancing policy registered by this package. It
+// spreads calls over ready endpoints by round robin or least outstanding
+// requests and ejects endpoints that keep failing.
+const balancerName = "stox_outlier_ejecting"
+
+func init() {
+	balancer.Register(&ejectingBuilder{})
+}
+
+// lbConfig is the balancer config carried in the gRPC service config
+type lbConfig struct {
+	serviceconfig.LoadBalancingConfig `json:"-"`
+
+	Policy              string `json:"policy"`
+	OutlierEnabled      bool   `json:"outlierEnabled"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	BaseEjectionMs      int64  `json:"baseEjectionMs"`
+	MaxEjectionPercent  int    `json:"maxEjectionPercent"`
+}
+
+// serviceConfigJSON returns the gRPC service config selecting the balancer for
+// cfg, or "" to keep gRPC's default pick_first
+func serviceConfigJSON(cfg config.LoadBalancingConfig) (string, error) {
+	switch cfg.Policy {
+	case "", "pick_first":
+		return "", nil
+	case "round_robin", "least_request":
+	default:
+		return "", fmt.Errorf("unknown load balancing policy %q", cfg.Policy)
+	}
+
+	lb := lbConfig{
+		Policy:              cfg.Policy,
+		OutlierEnabled:      cfg.OutlierDetection.Enabled,
+		ConsecutiveFailures: cfg.OutlierDetection.ConsecutiveFailures,
+		BaseEjectionMs:      cfg.OutlierDetection.BaseEjectionTime.Milliseconds(),
+		MaxEjectionPercent:  cfg.OutlierDetection.MaxEjectionPercent,
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"loadBalancingConfig": []map[string]interface{}{{balancerName: lb}},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// ejectingBuilder builds balancers that share gRPC's base balancer for
+// connection management and supply their own picker
+type ejectingBuilder struct{}
+
+func (*ejectingBuilder) Name() string {
+	return balancerName
+}
+
+func (*ejectingBuilder) ParseConfig(raw json.RawMessage) (serviceconfig.LoadBalancingConfig, error) {
+	cfg := &lbConfig{}
+	if err := json.Unmarshal(raw, cfg); err != nil {
+		return nil, fmt.Errorf("invalid %s config: %w", balancerName, err)
+	}
+	if cfg.ConsecutiveFailures < 1 {
+		cfg.ConsecutiveFailures = 5
+	}
+	if cfg.BaseEjectionMs <= 0 {
+		cfg.BaseEjectionMs = 30000
+	}
+	if cfg.MaxEjectionPercent <= 0 || cfg.MaxEjectionPercent > 100 {
+		cfg.MaxEjectionPercent = 50
+	}
+	return cfg, nil
+}
+
+func (*ejectingBuilder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pb := &ejectingPickerBuilder{stats: make(map[string]*endpointStats)}
+	inner := base.NewBalancerBuilder(balancerName, pb, base.Config{HealthCheck: true}).Build(cc, opts)
+	return &ejectingBalancer{Balancer: inner, pickerBuilder: pb}
+}
+
+// ejectingBalancer hands the parsed config to the picker builder
+type ejectingBalancer struct {
+	balancer.Balancer
+	pickerBuilder *ejectingPickerBuilder
+}
+
+func (b *ejectingBalancer) UpdateClientConnState(s balancer.ClientConnState) error {
+	if cfg, ok := s.BalancerConfig.(*lbConfig); ok {
+		b.pickerBuilder.setConfig(cfg)
+	}
+	return b.Balancer.UpdateClientConnState(s)
+}
+
+// endpointStats tracks one endpoint across picker rebuilds
+type endpointStats struct {
+	inFlight            atomic.Int64
+	consecutiveFailures atomic.Int64
+	ejectedUntil        atomic.Int64 // unix nanos, 0 when not ejected
+	ejections           atomic.Int64
+}
+
+// ejectingPickerBuilder builds pickers over the ready endpoints, keeping
+// per-endpoint stats keyed by address
+type ejectingPickerBuilder struct {
+	mu    sync.Mutex
+	cfg   *lbConfig
+	stats map[string]*endpointStats
+}
+
+func (pb *ejectingPickerBuilder) setConfig(cfg *lbConfig) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	pb.cfg = cfg
+}
+
+func (pb *ejectingPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+
+	cfg := pb.cfg
+	if cfg == nil {
+		cfg = &lbConfig{Policy: "round_robin"}
+	}
+
+	p := &ejectingPicker{cfg: cfg, next: rand.Uint32()}
+	seen := make(map[string]bool, len(info.ReadySCs))
+	for sc, scInfo := range info.ReadySCs {
+		addr := scInfo.Address.Addr
+		stats, ok := pb.stats[addr]
+		if !ok {
+			stats = &endpointStats{}
+			pb.stats[addr] = stats
+		}
+		seen[addr] = true
+		p.endpoints = append(p.endpoints, &pickerEndpoint{addr: addr, subConn: sc, stats: stats})
+	}
+
+	// Forget endpoints that left the resolver's address list
+	for addr := range pb.stats {
+		if !seen[addr] {
+			delete(pb.stats, addr)
+		}
+	}
+	return p
+}
+
+// pickerEndpoint is a ready endpoint in a picker
+type pickerEndpoint struct {
+	addr    string
+	subConn balancer.SubConn
+	stats   *endpointStats
+}
+
+// ejectingPicker picks among endpoints that are not ejected, falling back to
+// all of them if every endpoint is ejected
+type ejectingPicker struct {
+	cfg       *lbConfig
+	endpoints []*pickerEndpoint
+	next      uint32
+}
+
+func (p *ejectingPicker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	now := time.Now().UnixNano()
+	candidates := make([]*pickerEndpoint, 0, len(p.endpoints))
+	for _, ep := range p.endpoints {
+		if ep.stats.ejectedUntil.Load() <= now {
+			candidates = append(candidates, ep)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = p.endpoints
+	}
+
+	var chosen *pickerEndpoint
+	if p.cfg.Policy == "least_request" && len(candidates) > 1 {
+		// Power of two choices: compare two random endpoints
+		a := candidates[rand.Intn(len(candidates))]
+		b := candidates[rand.Intn(len(candidates))]
+		chosen = a
+		if b.stats.inFlight.Load() < a.stats.inFlight.Load() {
+			chosen = b
+		}
+	} else {
+		n := atomic.AddUint32(&p.next, 1)
+		chosen = candidates[int(n)%len(candidates)]
+	}
+
+	chosen.stats.inFlight.Add(1)
+	return balancer.PickResult{
+		SubConn: chosen.subConn,
+		Done: func(done balancer.DoneInfo) {
+			chosen.stats.inFlight.Add(-1)
+			p.recordOutcome(info.Ctx, chosen, done.Err)
+		},
+	}, nil
+}
+
+// recordOutcome counts consecutive failures and ejects an endpoint that
+// reaches the threshold, unless that would eject more than the allowed share.
+// Calls the caller gave up on are not counted either way.
+func (p *ejectingPicker) recordOutcome(ctx context.Context, ep *pickerEndpoint, err error) {
+	if !p.cfg.OutlierEnabled {
+		return
+	}
+	if err != nil && callerDone(ctx) {
+		return
+	}
+	if err == nil || !isBackendFailure(err) {
+		ep.stats.consecutiveFailures.Store(0)
+		return
+	}
+	if ep.stats.consecutiveFailures.Add(1) < int64(p.cfg.ConsecutiveFailures) {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	ejected := 0
+	for _, other := range p.endpoints {
+		if other.stats.ejectedUntil.Load() > now {
+			ejected++
+		}
+	}
+	if (ejected+1)*100 > len(p.endpoints)*p.cfg.MaxEjectionPercent {
+		return
+	}
+
+	// Repeat offenders stay out longer, up to ten times the base
+	times := ep.stats.ejections.Add(1)
+	if times > 10 {
+		times = 10
+	}
+	duration := time.Duration(p.cfg.BaseEjectionMs) * time.Millisecond * time.Duration(times)
+	ep.stats.ejectedUntil.Store(now + int64(duration))
+	ep.stats.consecutiveFailures.Store(0)
+
+	zap.L().Warn("Ejected failing backend endpoint",
+		zap.String("address", ep.addr),
+		zap.Duration("duration", duration),
+	)
+}
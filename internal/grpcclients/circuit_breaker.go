@@ -0,0 +1,52 @@
+package grpcclients
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"stox-gateway/internal/breaker"
+)
+
+// isBackendFailure reports whether an RPC error says the backend is unhealthy.
+// Errors about the request itself, such as InvalidArgument or NotFound, do not
+// count against the circuit. Neither does ResourceExhausted: it reports a
+// user's quota or a message over the size limit far more often than an
+// overloaded backend, and must not open the circuit for everyone.
+func isBackendFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
+
+// breakerInterceptor fails calls fast with a *breaker.OpenError while the
+// circuit is open. It runs outside the retry interceptor so that a call and
+// all of its retries count as one outcome.
+func breakerInterceptor(b *breaker.Breaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if isHealthCheck(method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		done, err := b.Allow()
+		if err != nil {
+			return err
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(callOutcome(ctx, err))
+		return err
+	}
+}
+
+// callOutcome classifies a finished call for the circuit breaker. A call that
+// failed because the caller cancelled it or its own deadline passed is
+// ignored; only deadlines set by our timeouts count as backend failures.
+func callOutcome(ctx context.Context, err error) breaker.Outcome {
+	if err != nil && callerDone(ctx) {
+		return breaker.Ignored
+	}
+	return breaker.OutcomeOf(isBackendFailure(err))
+}
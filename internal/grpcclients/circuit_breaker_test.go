@@ -0,0 +1,81 @@
+package grpcclients
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"stox-gateway/internal/breaker"
+)
+
+func TestIsBackendFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{status.Error(codes.Unavailable, "connection refused"), true},
+		{status.Error(codes.DeadlineExceeded, "deadline exceeded"), true},
+		{status.Error(codes.Internal, "internal"), true},
+		{status.Error(codes.Unknown, "unknown"), true},
+		{errors.New("not a status"), true},
+		{status.Error(codes.InvalidArgument, "bad image"), false},
+		{status.Error(codes.NotFound, "not found"), false},
+		{status.Error(codes.PermissionDenied, "denied"), false},
+		{status.Error(codes.Unauthenticated, "no token"), false},
+		{status.Error(codes.Canceled, "canceled"), false},
+		{status.Error(codes.ResourceExhausted, "quota exceeded"), false},
+		{status.Error(codes.ResourceExhausted, "grpc: received message larger than max"), false},
+	}
+
+	for _, tt := range tests {
+		if got := isBackendFailure(tt.err); got != tt.want {
+			t.Errorf("isBackendFailure(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCallOutcome(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	cancelled, cancelNow := context.WithCancel(context.Background())
+	cancelNow()
+
+	// ctx returns the context the breaker sees: the caller's, remembered by
+	// the timeout interceptor, with our own timeout applied
+	ctx := func(caller context.Context, timeout time.Duration) context.Context {
+		ctx, cancel := context.WithTimeout(withCaller(caller), timeout)
+		t.Cleanup(cancel)
+		return ctx
+	}
+	deadline := status.Error(codes.DeadlineExceeded, "deadline exceeded")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want breaker.Outcome
+	}{
+		{"success", ctx(context.Background(), time.Minute), nil, breaker.Success},
+		{"request error", ctx(context.Background(), time.Minute), status.Error(codes.InvalidArgument, "bad"), breaker.Success},
+		{"backend failure", ctx(context.Background(), time.Minute), status.Error(codes.Unavailable, "down"), breaker.Failure},
+		{"our timeout", ctx(context.Background(), -time.Second), deadline, breaker.Failure},
+		{"caller deadline", ctx(expired, time.Minute), deadline, breaker.Ignored},
+		{"caller cancelled", ctx(cancelled, time.Minute), status.Error(codes.Canceled, "canceled"), breaker.Ignored},
+		{"success despite caller giving up", ctx(cancelled, time.Minute), nil, breaker.Success},
+		{"caller done without timeout interceptor", expired, deadline, breaker.Ignored},
+		{"backend failure without timeout interceptor", context.Background(), deadline, breaker.Failure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := callOutcome(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("callOutcome() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package grpcclients
+
+import (
+	"context"
+	"net"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"stox-gateway/internal/config"
+	"stox-gateway/internal/tlsutil"
+)
+
+// reloadingCredentials performs each client handshake with the current CA
+// bundle and client certificate, so rotated files apply to new connections
+type reloadingCredentials struct {
+	credentials.TransportCredentials
+	tlsConfig *tlsutil.ClientConfig
+}
+
+// ClientHandshake implements credentials.TransportCredentials
+func (c *reloadingCredentials) ClientHandshake(ctx context.Context, authority string, rawConn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.tlsConfig.Config()).ClientHandshake(ctx, authority, rawConn)
+}
+
+// Clone implements credentials.TransportCredentials
+func (c *reloadingCredentials) Clone() credentials.TransportCredentials {
+	return &reloadingCredentials{
+		TransportCredentials: c.TransportCredentials.Clone(),
+		tlsConfig:            c.tlsConfig,
+	}
+}
+
+// transportCredentials returns the dial option for a service's TLS settings
+// and a function that stops watching the certificate files
+func transportCredentials(service string, cfg config.ClientTLSConfig, logger *zap.Logger) (grpc.DialOption, func(), error) {
+	if !cfg.Enabled {
+		logger.Warn("Connecting without TLS", zap.String("service", service))
+		return grpc.WithTransportCredentials(insecure.NewCredentials()), func() {}, nil
+	}
+
+	tlsConfig, err := tlsutil.NewClientConfig(cfg, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logger.Info("Connecting with TLS",
+		zap.String("service", service),
+		zap.Bool("mtls", cfg.CertFile != ""),
+		zap.String("serverName", cfg.ServerName),
+	)
+
+	ctx, stop := context.WithCancel(context.Background())
+	go tlsutil.Watch(ctx, cfg.ReloadInterval, tlsConfig.Reloaders...)
+
+	creds := &reloadingCredentials{
+		TransportCredentials: credentials.NewTLS(tlsConfig.Config()),
+		tlsConfig:            tlsConfig,
+	}
+	return grpc.WithTransportCredentials(creds), stop, nil
+}
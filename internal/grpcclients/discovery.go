@@ -0,0 +1,187 @@
+package grpcclients
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+
+	"stox-gateway/internal/config"
+)
+
+// Resolver schemes for static and file discovery
+const (
+	staticScheme = "static"
+	fileScheme   = "discoveryfile"
+)
+
+// dialTarget returns the gRPC target for a backend at host:port and the dial
+// options that resolve and balance across its endpoints
+func dialTarget(service, host string, port int, cfg config.ServiceConfig, logger *zap.Logger) (string, []grpc.DialOption, error) {
+	address := fmt.Sprintf("%s:%d", host, port)
+
+	var target string
+	var opts []grpc.DialOption
+	switch cfg.Discovery.Mode {
+	case "":
+		target = address
+	case "dns":
+		target = "dns:///" + address
+	case "static":
+		if len(cfg.Discovery.Endpoints) == 0 {
+			return "", nil, fmt.Errorf("static discovery for %s service has no endpoints", service)
+		}
+		r := manual.NewBuilderWithScheme(staticScheme)
+		r.InitialState(resolver.State{Addresses: toAddresses(cfg.Discovery.Endpoints)})
+		target = staticScheme + ":///" + service
+		// Certificates name the service, not the synthetic target
+		opts = append(opts, grpc.WithResolvers(r), grpc.WithAuthority(host))
+	case "file":
+		if _, err := os.Stat(cfg.Discovery.File); err != nil {
+			return "", nil, fmt.Errorf("discovery file for %s service: %w", service, err)
+		}
+		builder := &fileResolverBuilder{
+			path:     cfg.Discovery.File,
+			interval: cfg.Discovery.RefreshInterval,
+			logger:   logger.With(zap.String("service", service)),
+		}
+		target = fileScheme + ":///" + service
+		opts = append(opts, grpc.WithResolvers(builder), grpc.WithAuthority(host))
+	default:
+		return "", nil, fmt.Errorf("unknown discovery mode %q for %s service", cfg.Discovery.Mode, service)
+	}
+
+	serviceConfig, err := serviceConfigJSON(cfg.LoadBalancing)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid load balancing for %s service: %w", service, err)
+	}
+	if serviceConfig != "" {
+		opts = append(opts, grpc.WithDefaultServiceConfig(serviceConfig))
+	}
+
+	return target, opts, nil
+}
+
+// toAddresses converts host:port strings to resolver addresses
+func toAddresses(endpoints []string) []resolver.Address {
+	addresses := make([]resolver.Address, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		addresses = append(addresses, resolver.Address{Addr: endpoint})
+	}
+	return addresses
+}
+
+// fileResolverBuilder resolves endpoints from a file listing one host:port
+// per line. Blank lines and lines starting with # are ignored.
+type fileResolverBuilder struct {
+	path     string
+	interval time.Duration
+	logger   *zap.Logger
+}
+
+func (b *fileResolverBuilder) Scheme() string {
+	return fileScheme
+}
+
+func (b *fileResolverBuilder) Build(_ resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	interval := b.interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	r := &fileResolver{
+		path:     b.path,
+		interval: interval,
+		cc:       cc,
+		logger:   b.logger,
+		refresh:  make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	r.update()
+	go r.watch()
+	return r, nil
+}
+
+// fileResolver polls the discovery file and pushes changes to gRPC
+type fileResolver struct {
+	path     string
+	interval time.Duration
+	cc       resolver.ClientConn
+	logger   *zap.Logger
+	refresh  chan struct{}
+	done     chan struct{}
+
+	last []byte
+}
+
+func (r *fileResolver) watch() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		case <-r.refresh:
+		}
+		r.update()
+	}
+}
+
+// update re-reads the file and reports its endpoints if they changed. Only
+// the watch goroutine and Build call it, never concurrently.
+func (r *fileResolver) update() {
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		r.logger.Warn("Failed to read discovery file, keeping previous endpoints", zap.String("file", r.path), zap.Error(err))
+		if r.last == nil {
+			r.cc.ReportError(err)
+		}
+		return
+	}
+	if r.last != nil && bytes.Equal(data, r.last) {
+		return
+	}
+
+	var endpoints []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		endpoints = append(endpoints, line)
+	}
+	if len(endpoints) == 0 {
+		r.logger.Warn("Discovery file lists no endpoints, keeping previous endpoints", zap.String("file", r.path))
+		if r.last == nil {
+			r.cc.ReportError(fmt.Errorf("discovery file %s lists no endpoints", r.path))
+		}
+		return
+	}
+
+	if err := r.cc.UpdateState(resolver.State{Addresses: toAddresses(endpoints)}); err != nil {
+		r.logger.Warn("Backend rejected discovered endpoints", zap.Error(err))
+	}
+	r.last = data
+	r.logger.Info("Discovered backend endpoints", zap.Strings("endpoints", endpoints))
+}
+
+func (r *fileResolver) ResolveNow(resolver.ResolveNowOptions) {
+	select {
+	case r.refresh <- struct{}{}:
+	default:
+	}
+}
+
+func (r *fileResolver) Close() {
+	close(r.done)
+}
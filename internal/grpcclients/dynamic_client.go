@@ -0,0 +1,101 @@
+package grpcclients
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"stox-gateway/internal/breaker"
+	"stox-gateway/internal/config"
+)
+
+// DynamicClient invokes arbitrary unary methods on a backend whose messages
+// are only known at runtime, e.g. dynamicpb messages built from descriptors
+type DynamicClient struct {
+	name       string
+	conn       *grpc.ClientConn
+	stopReload func()
+	logger     *zap.Logger
+}
+
+// NewDynamicClient creates a client for the named backend service
+func NewDynamicClient(name string, cfg config.ServiceConfig, logger *zap.Logger) (*DynamicClient, error) {
+	address := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+
+	logger.Info("Connecting to service",
+		zap.String("service", name),
+		zap.String("address", address),
+	)
+
+	creds, stopReload, err := transportCredentials(name, cfg.TLS, logger)
+	if err != nil {
+		logger.Error("Failed to configure TLS for service", zap.String("service", name), zap.Error(err))
+		return nil, fmt.Errorf("failed to configure TLS for %s service: %w", name, err)
+	}
+
+	retry, err := retryInterceptor(name, cfg.RetryPolicies, logger)
+	if err != nil {
+		stopReload()
+		return nil, err
+	}
+
+	target, targetOpts, err := dialTarget(name, cfg.Host, cfg.Port, cfg, logger)
+	if err != nil {
+		stopReload()
+		return nil, err
+	}
+
+	transportOpts, err := transportOptions(name, cfg)
+	if err != nil {
+		stopReload()
+		return nil, err
+	}
+
+	circuit := breaker.New(name, cfg.CircuitBreaker, logger)
+
+	opts := append(targetOpts, transportOpts...)
+	opts = append(opts, creds, grpc.WithChainUnaryInterceptor(timeoutInterceptor(cfg), breakerInterceptor(circuit), retry))
+	conn, err := grpc.NewClient(target, opts...)
+	if err != nil {
+		stopReload()
+		logger.Error("Failed to connect to service", zap.String("service", name), zap.Error(err))
+		return nil, fmt.Errorf("failed to connect to %s service: %v", name, err)
+	}
+
+	return &DynamicClient{
+		name:       name,
+		conn:       conn,
+		stopReload: stopReload,
+		logger:     logger,
+	}, nil
+}
+
+// Close closes the gRPC connection
+func (c *DynamicClient) Close() error {
+	c.logger.Info("Closing connection to service", zap.String("service", c.name))
+	c.stopReload()
+	return c.conn.Close()
+}
+
+// Invoke calls a unary method such as "/llm.LLMService/Generate". The gRPC
+// status of a failed call is returned unchanged so callers can map it.
+func (c *DynamicClient) Invoke(ctx context.Context, method string, req, resp proto.Message) error {
+	c.logger.Debug("Invoking method",
+		zap.String("service", c.name),
+		zap.String("method", method),
+	)
+
+	if err := c.conn.Invoke(ctx, method, req, resp); err != nil {
+		c.logger.Error("Method invocation failed",
+			zap.String("service", c.name),
+			zap.String("method", method),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package grpcclients
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc/status"
+)
+
+// Error is returned when a backend call fails with a gRPC status. It keeps
+// the status, including its code and error details, so that status.FromError
+// and status.Code see the backend's answer rather than codes.Unknown.
+type Error struct {
+	// Op names the failed operation, e.g. "process image"
+	Op     string
+	Status *status.Status
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Op, e.Status.Message())
+}
+
+// GRPCStatus returns the backend's status
+func (e *Error) GRPCStatus() *status.Status {
+	return e.Status
+}
+
+// callError wraps a failed call's error for op. Errors that carry no gRPC
+// status, such as an open circuit, are wrapped with %w instead.
+func callError(op string, err error) error {
+	if st, ok := status.FromError(err); ok {
+		return &Error{Op: op, Status: st}
+	}
+	return fmt.Errorf("%s failed: %w", op, err)
+}
@@ -0,0 +1,74 @@
+package grpcclients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// isHealthCheck reports whether method is the grpc.health.v1 Check. Health
+// checks skip the circuit breaker and retries: a probe must neither count
+// against the circuit nor take one of its half-open slots.
+func isHealthCheck(method string) bool {
+	return method == healthpb.Health_Check_FullMethodName
+}
+
+// checkServing asks a backend's grpc.health.v1 service for its overall status
+func checkServing(ctx context.Context, conn *grpc.ClientConn) error {
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("backend reports %s", resp.Status)
+	}
+	return nil
+}
+
+// CheckHealth checks that the auth service is serving
+func (c *AuthClient) CheckHealth(ctx context.Context) error {
+	return checkServing(ctx, c.conn)
+}
+
+// CheckHealth checks that at least one image service variant is serving.
+// Variants are checked concurrently, so a hanging variant cannot use up the
+// time of the others.
+func (c *ImageClient) CheckHealth(ctx context.Context) error {
+	errs := make(chan error, len(c.variants))
+	for _, variant := range c.variants {
+		go func(variant *imageVariant) {
+			if err := checkServing(ctx, variant.conn); err != nil {
+				errs <- fmt.Errorf("variant %s: %w", variant.name, err)
+				return
+			}
+			errs <- nil
+		}(variant)
+	}
+
+	var failures []error
+	for range c.variants {
+		err := <-errs
+		if err == nil {
+			return nil
+		}
+		failures = append(failures, err)
+	}
+	return errors.Join(failures...)
+}
+
+// VariantChecks returns a health check of each image service variant by
+// name. They report on variants such as a canary without deciding readiness,
+// which CheckHealth does.
+func (c *ImageClient) VariantChecks() map[string]func(context.Context) error {
+	checks := make(map[string]func(context.Context) error, len(c.variants))
+	for _, variant := range c.variants {
+		conn := variant.conn
+		checks[variant.name] = func(ctx context.Context) error {
+			return checkServing(ctx, conn)
+		}
+	}
+	return checks
+}
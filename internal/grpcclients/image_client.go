@@ -3,58 +3,272 @@ package grpcclients
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"stox-gateway/internal/breaker"
+	"stox-gateway/internal/config"
 	pb "stox-gateway/internal/proto/image-service"
 )
 
-// ImageClient represents a gRPC client for the image service
-type ImageClient struct {
+// DefaultImageVariant names the single backend used when no variants are configured
+const DefaultImageVariant = "default"
+
+type imageVariantKey struct{}
+
+// WithImageVariant returns a context that routes ProcessImage to the named variant
+func WithImageVariant(ctx context.Context, variant string) context.Context {
+	return context.WithValue(ctx, imageVariantKey{}, variant)
+}
+
+// imageVariant is one weighted image-service backend
+type imageVariant struct {
+	name   string
+	weight int
 	client pb.ImageServiceClient
 	conn   *grpc.ClientConn
-	logger *zap.Logger
+
+	// streamUnsupported and progressUnsupported are set once the backend
+	// answers Unimplemented to ProcessImageStream or ProcessImageWithProgress
+	streamUnsupported   atomic.Bool
+	progressUnsupported atomic.Bool
 }
 
-// NewImageClient creates a new image client
-func NewImageClient(host string, port int, logger *zap.Logger) (*ImageClient, error) {
-	address := fmt.Sprintf("%s:%d", host, port)
+// ImageClient represents a gRPC client for the image service
+type ImageClient struct {
+	variants       []*imageVariant
+	totalWeight    int
+	sticky         bool
+	overrideHeader string
+	shadow         *imageShadow
+	circuit        *breaker.Breaker
+	streaming      config.StreamingConfig
+	maxRecvSize    int
+	timeouts       callTimeouts
+	stopReload     func()
+	logger         *zap.Logger
+}
 
-	logger.Info("Connecting to image service",
-		zap.String("address", address),
-	)
+// NewImageClient creates a new image client with a connection to every
+// configured variant
+func NewImageClient(cfg config.ServiceConfig, logger *zap.Logger) (*ImageClient, error) {
+	variants := cfg.Variants
+	if len(variants) > 0 && (cfg.Discovery.Mode == "static" || cfg.Discovery.Mode == "file") {
+		return nil, fmt.Errorf("%s discovery cannot be combined with image service variants", cfg.Discovery.Mode)
+	}
+	if cfg.Streaming.Enabled && cfg.Streaming.ChunkSize <= 0 {
+		return nil, fmt.Errorf("image service streaming chunk size must be positive")
+	}
+	if len(variants) == 0 {
+		variants = []config.ServiceVariant{{Name: DefaultImageVariant, Host: cfg.Host, Port: cfg.Port, Weight: 1}}
+	}
 
-	// Create insecure connection (use TLS in production)
-	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// Variants and the shadow share the service's TLS settings
+	creds, stopReload, err := transportCredentials("image", cfg.TLS, logger)
 	if err != nil {
-		logger.Error("Failed to connect to image service", zap.Error(err))
-		return nil, fmt.Errorf("failed to connect to image service: %v", err)
+		logger.Error("Failed to configure TLS for image service", zap.Error(err))
+		return nil, fmt.Errorf("failed to configure TLS for image service: %w", err)
 	}
 
-	logger.Info("Connected to image service")
+	// Retries and hedging apply to the variants; the shadow is attempted once
+	retry, err := retryInterceptor("image", cfg.RetryPolicies, logger)
+	if err != nil {
+		stopReload()
+		return nil, err
+	}
+
+	// Message limits, keepalive and compression apply to the shadow as well
+	transportOpts, err := transportOptions("image", cfg)
+	if err != nil {
+		stopReload()
+		return nil, err
+	}
+
+	maxRecvSize := cfg.MaxRecvMessageSize
+	if maxRecvSize <= 0 {
+		maxRecvSize = defaultMaxRecvMessageSize
+	}
+
+	c := &ImageClient{
+		sticky:         cfg.Routing.Strategy == "sticky",
+		overrideHeader: cfg.Routing.OverrideHeader,
+		circuit:        breaker.New("image", cfg.CircuitBreaker, logger),
+		streaming:      cfg.Streaming,
+		maxRecvSize:    maxRecvSize,
+		timeouts:       newCallTimeouts(cfg),
+		stopReload:     stopReload,
+		logger:         logger,
+	}
+
+	for _, variant := range variants {
+		if variant.Weight < 0 {
+			c.Close()
+			return nil, fmt.Errorf("image service variant %s has a negative weight", variant.Name)
+		}
+
+		address := fmt.Sprintf("%s:%d", variant.Host, variant.Port)
+
+		logger.Info("Connecting to image service",
+			zap.String("variant", variant.Name),
+			zap.String("address", address),
+			zap.Int("weight", variant.Weight),
+		)
+
+		target, targetOpts, err := dialTarget("image", variant.Host, variant.Port, cfg, logger)
+		if err != nil {
+			c.Close()
+			return nil, err
+		}
+
+		// One circuit covers every variant
+		opts := append(targetOpts, transportOpts...)
+		opts = append(opts, creds, grpc.WithChainUnaryInterceptor(timeoutInterceptor(cfg), breakerInterceptor(c.circuit), retry))
+		conn, err := grpc.NewClient(target, opts...)
+		if err != nil {
+			logger.Error("Failed to connect to image service", zap.Error(err))
+			c.Close()
+			return nil, fmt.Errorf("failed to connect to image service: %v", err)
+		}
+
+		c.variants = append(c.variants, &imageVariant{
+			name:   variant.Name,
+			weight: variant.Weight,
+			client: pb.NewImageServiceClient(conn),
+			conn:   conn,
+		})
+		c.totalWeight += variant.Weight
+	}
+
+	if c.totalWeight == 0 {
+		c.Close()
+		return nil, fmt.Errorf("image service variants have no weight")
+	}
+
+	if cfg.Shadow.Enabled {
+		shadow, err := newImageShadow(cfg.Shadow, append(transportOpts, creds), logger)
+		if err != nil {
+			logger.Error("Failed to connect to shadow image service", zap.Error(err))
+			c.Close()
+			return nil, err
+		}
+		c.shadow = shadow
+	}
 
-	client := pb.NewImageServiceClient(conn)
+	logger.Info("Connected to image service")
 
-	return &ImageClient{
-		client: client,
-		conn:   conn,
-		logger: logger,
-	}, nil
+	return c, nil
 }
 
-// Close closes the gRPC connection
+// Close closes the gRPC connections
 func (c *ImageClient) Close() error {
 	c.logger.Info("Closing connection to image service")
-	return c.conn.Close()
+	c.stopReload()
+
+	var firstErr error
+	for _, variant := range c.variants {
+		if err := variant.conn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if c.shadow != nil {
+		if err := c.shadow.close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// ShadowStats returns the aggregate shadow comparison, or false when shadow
+// traffic is not configured
+func (c *ImageClient) ShadowStats() (ShadowStats, bool) {
+	if c.shadow == nil {
+		return ShadowStats{}, false
+	}
+	return c.shadow.stats(), true
+}
+
+// CircuitOpen reports whether calls to the image service are currently
+// being rejected by its circuit breaker
+func (c *ImageClient) CircuitOpen() bool {
+	return c.circuit.State() == breaker.Open
+}
+
+// OverrideHeader returns the request header that can force a variant, if any
+func (c *ImageClient) OverrideHeader() string {
+	return c.overrideHeader
+}
+
+// ChooseVariant picks the variant for a request. A known override always
+// wins; otherwise sticky routing hashes the user ID so a user keeps seeing the
+// same variant, and percentage routing picks at random by weight.
+func (c *ImageClient) ChooseVariant(userID, override string) string {
+	if override != "" {
+		for _, variant := range c.variants {
+			if variant.name == override {
+				return variant.name
+			}
+		}
+		c.logger.Warn("Ignoring unknown image service variant override", zap.String("variant", override))
+	}
+
+	var point int
+	if c.sticky && userID != "" {
+		h := fnv.New32a()
+		h.Write([]byte(userID))
+		point = int(h.Sum32() % uint32(c.totalWeight))
+	} else {
+		point = rand.Intn(c.totalWeight)
+	}
+
+	for _, variant := range c.variants {
+		if point < variant.weight {
+			return variant.name
+		}
+		point -= variant.weight
+	}
+	return c.variants[len(c.variants)-1].name
+}
+
+// variantFor resolves the variant chosen for ctx, choosing one if none was set
+func (c *ImageClient) variantFor(ctx context.Context) *imageVariant {
+	name, _ := ctx.Value(imageVariantKey{}).(string)
+	if name == "" {
+		name = c.ChooseVariant("", "")
+	}
+	for _, variant := range c.variants {
+		if variant.name == name {
+			return variant
+		}
+	}
+	return c.variants[0]
 }
 
-// ProcessImage processes an image using the image service
+// ProcessImage processes an image using the image service variant selected
+// with WithImageVariant
 func (c *ImageClient) ProcessImage(ctx context.Context, imageData []byte, mimeType, productName string) (*pb.ProcessImageResponse, error) {
+	return c.processImage(ctx, imageData, mimeType, productName, nil)
+}
+
+// ProcessImageWithProgress is ProcessImage with progress updates passed to
+// onProgress while the image is processed. Backends without progress
+// reporting, and images large enough to be streamed in chunks, are processed
+// without updates.
+func (c *ImageClient) ProcessImageWithProgress(ctx context.Context, imageData []byte, mimeType, productName string, onProgress func(*pb.ProcessImageProgress)) (*pb.ProcessImageResponse, error) {
+	return c.processImage(ctx, imageData, mimeType, productName, onProgress)
+}
+
+func (c *ImageClient) processImage(ctx context.Context, imageData []byte, mimeType, productName string, onProgress func(*pb.ProcessImageProgress)) (*pb.ProcessImageResponse, error) {
+	variant := c.variantFor(ctx)
+
 	c.logger.Debug("Processing image",
+		zap.String("variant", variant.name),
 		zap.String("mimeType", mimeType),
 		zap.String("productName", productName),
 		zap.Int("imageSize", len(imageData)),
@@ -66,20 +280,40 @@ func (c *ImageClient) ProcessImage(ctx context.Context, imageData []byte, mimeTy
 		ProductName: productName,
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, 60*time.Second) // Longer timeout for image processing
-	defer cancel()
+	// Mirror a sample of requests to the shadow backend; this never blocks
+	reportToShadow := func(primaryOutcome) {}
+	if c.shadow != nil {
+		reportToShadow = c.shadow.mirror(req)
+	}
 
-	resp, err := c.client.ProcessImage(ctx, req)
-	if err != nil {
-		c.logger.Error("Process image request failed", zap.Error(err))
-		st, ok := status.FromError(err)
-		if ok {
-			return nil, fmt.Errorf("process image failed: %s", st.Message())
+	start := time.Now()
+	var resp *pb.ProcessImageResponse
+	var err error
+	if c.useStream(variant, len(imageData)) {
+		resp, err = c.processImageStream(ctx, variant, req)
+		if status.Code(err) == codes.Unimplemented {
+			variant.streamUnsupported.Store(true)
+			c.logger.Warn("Image service does not support streaming, falling back to unary calls", zap.String("variant", variant.name))
+			resp, err = variant.client.ProcessImage(ctx, req)
+		}
+	} else if onProgress != nil && c.useProgress(variant) {
+		resp, err = c.processImageWithProgress(ctx, variant, req, onProgress)
+		if status.Code(err) == codes.Unimplemented {
+			variant.progressUnsupported.Store(true)
+			c.logger.Warn("Image service does not report progress, falling back to unary calls", zap.String("variant", variant.name))
+			resp, err = variant.client.ProcessImage(ctx, req)
 		}
-		return nil, fmt.Errorf("process image failed: %v", err)
+	} else {
+		resp, err = variant.client.ProcessImage(ctx, req)
+	}
+	reportToShadow(primaryOutcome{latency: time.Since(start), size: len(resp.GetProcessedImageData()), err: err})
+	if err != nil {
+		c.logger.Error("Process image request failed", zap.String("variant", variant.name), zap.Error(err))
+		return nil, callError("process image", err)
 	}
 
 	c.logger.Debug("Process image request successful",
+		zap.String("variant", variant.name),
 		zap.String("responseMimeType", resp.MimeType),
 		zap.String("message", resp.Message),
 		zap.Int("processedImageSize", len(resp.ProcessedImageData)),
@@ -0,0 +1,175 @@
+package grpcclients
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	"stox-gateway/internal/config"
+	pb "stox-gateway/internal/proto/image-service"
+)
+
+// ShadowStats aggregates the comparison between primary and shadow calls
+type ShadowStats struct {
+	Mirrored            int64 `json:"mirrored"`
+	Dropped             int64 `json:"dropped"`
+	PrimaryErrors       int64 `json:"primaryErrors"`
+	ShadowErrors        int64 `json:"shadowErrors"`
+	TotalLatencyDiffMs  int64 `json:"totalLatencyDiffMs"`
+	TotalOutputSizeDiff int64 `json:"totalOutputSizeDiff"`
+}
+
+// primaryOutcome is the result of the user-facing call, handed to the shadow
+// goroutine for comparison
+type primaryOutcome struct {
+	latency time.Duration
+	size    int
+	err     error
+}
+
+// imageShadow mirrors sampled ProcessImage calls to a candidate backend
+type imageShadow struct {
+	client     pb.ImageServiceClient
+	conn       *grpc.ClientConn
+	sampleRate float64
+	timeout    time.Duration
+	slots      chan struct{}
+	logger     *zap.Logger
+
+	mirrored            atomic.Int64
+	dropped             atomic.Int64
+	primaryErrors       atomic.Int64
+	shadowErrors        atomic.Int64
+	totalLatencyDiffMs  atomic.Int64
+	totalOutputSizeDiff atomic.Int64
+}
+
+// newImageShadow connects to the shadow backend
+func newImageShadow(cfg config.ShadowConfig, opts []grpc.DialOption, logger *zap.Logger) (*imageShadow, error) {
+	address := fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+
+	logger.Info("Connecting to shadow image service",
+		zap.String("address", address),
+		zap.Float64("sampleRate", cfg.SampleRate),
+		zap.Int("maxConcurrency", cfg.MaxConcurrency),
+	)
+
+	conn, err := grpc.NewClient(address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to shadow image service: %v", err)
+	}
+
+	maxConcurrency := cfg.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = 1
+	}
+
+	return &imageShadow{
+		client:     pb.NewImageServiceClient(conn),
+		conn:       conn,
+		sampleRate: cfg.SampleRate,
+		timeout:    cfg.Timeout,
+		slots:      make(chan struct{}, maxConcurrency),
+		logger:     logger,
+	}, nil
+}
+
+// mirror starts a shadow call for a sampled request and returns a function
+// that reports the primary outcome to it. It never blocks: when every slot is
+// busy the request is simply not mirrored. The returned function is always
+// safe to call.
+func (s *imageShadow) mirror(req *pb.ProcessImageRequest) func(primaryOutcome) {
+	if rand.Float64() >= s.sampleRate {
+		return func(primaryOutcome) {}
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		s.dropped.Add(1)
+		return func(primaryOutcome) {}
+	}
+
+	primary := make(chan primaryOutcome, 1)
+	go func() {
+		defer func() { <-s.slots }()
+
+		// Detached from the user request so that neither can cancel the other
+		ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+
+		start := time.Now()
+		resp, err := s.client.ProcessImage(ctx, req)
+		shadowLatency := time.Since(start)
+
+		outcome := <-primary
+		s.record(outcome, shadowLatency, resp, err)
+	}()
+
+	return func(outcome primaryOutcome) {
+		primary <- outcome
+	}
+}
+
+// record logs the comparison and updates the aggregate stats
+func (s *imageShadow) record(primary primaryOutcome, shadowLatency time.Duration, resp *pb.ProcessImageResponse, err error) {
+	shadowSize := 0
+	if resp != nil {
+		shadowSize = len(resp.ProcessedImageData)
+	}
+	latencyDiff := shadowLatency - primary.latency
+	sizeDiff := shadowSize - primary.size
+
+	s.mirrored.Add(1)
+	if primary.err != nil {
+		s.primaryErrors.Add(1)
+	}
+	if err != nil {
+		s.shadowErrors.Add(1)
+	}
+	if primary.err == nil && err == nil {
+		s.totalLatencyDiffMs.Add(latencyDiff.Milliseconds())
+		s.totalOutputSizeDiff.Add(int64(sizeDiff))
+	}
+
+	fields := []zap.Field{
+		zap.Duration("primaryLatency", primary.latency),
+		zap.Duration("shadowLatency", shadowLatency),
+		zap.Duration("latencyDiff", latencyDiff),
+		zap.Int("primarySize", primary.size),
+		zap.Int("shadowSize", shadowSize),
+		zap.Int("sizeDiff", sizeDiff),
+		zap.Bool("primaryError", primary.err != nil),
+	}
+	if err != nil {
+		fields = append(fields, zap.NamedError("shadowError", err))
+	}
+	s.logger.Info("Shadow image processing compared", fields...)
+}
+
+// stats returns a snapshot of the aggregate comparison
+func (s *imageShadow) stats() ShadowStats {
+	return ShadowStats{
+		Mirrored:            s.mirrored.Load(),
+		Dropped:             s.dropped.Load(),
+		PrimaryErrors:       s.primaryErrors.Load(),
+		ShadowErrors:        s.shadowErrors.Load(),
+		TotalLatencyDiffMs:  s.totalLatencyDiffMs.Load(),
+		TotalOutputSizeDiff: s.totalOutputSizeDiff.Load(),
+	}
+}
+
+// close waits for in-flight shadow calls, each bounded by the shadow timeout,
+// then closes the shadow connection. Taking every slot also stops new
+// requests from being mirrored.
+func (s *imageShadow) close() error {
+	for i := 0; i < cap(s.slots); i++ {
+		s.slots <- struct{}{}
+	}
+	return s.conn.Close()
+}
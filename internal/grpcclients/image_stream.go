@@ -0,0 +1,162 @@
+package grpcclients
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "stox-gateway/internal/proto/image-service"
+)
+
+// maxPreallocation bounds the buffer reserved from the size the server
+// announces, so that a bad announcement cannot exhaust memory
+const maxPreallocation = 64 * 1024 * 1024
+
+// defaultMaxRecvMessageSize is gRPC's default receive limit, which also
+// bounds a streamed image when max_recv_message_size is not set
+const defaultMaxRecvMessageSize = 4 * 1024 * 1024
+
+// useProgress reports whether progress can be requested from the variant
+func (c *ImageClient) useProgress(variant *imageVariant) bool {
+	return !variant.progressUnsupported.Load()
+}
+
+// useStream reports whether an image of size bytes should be sent to the
+// variant over ProcessImageStream
+func (c *ImageClient) useStream(variant *imageVariant, size int) bool {
+	return c.streaming.Enabled && size >= c.streaming.Threshold && !variant.streamUnsupported.Load()
+}
+
+// guardStream runs a streaming call under the circuit breaker and the
+// method timeout, which stream calls do not get from the unary interceptors
+func (c *ImageClient) guardStream(ctx context.Context, method string, call func(context.Context) (*pb.ProcessImageResponse, error)) (*pb.ProcessImageResponse, error) {
+	ctx = withCaller(ctx)
+	if timeout := c.timeouts.forMethod(method); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	// Returning early must end the stream
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done, err := c.circuit.Allow()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := call(ctx)
+	done(callOutcome(ctx, err))
+	return resp, err
+}
+
+// processImageStream sends req to the variant over ProcessImageStream
+func (c *ImageClient) processImageStream(ctx context.Context, variant *imageVariant, req *pb.ProcessImageRequest) (*pb.ProcessImageResponse, error) {
+	return c.guardStream(ctx, "ProcessImageStream", func(ctx context.Context) (*pb.ProcessImageResponse, error) {
+		return c.streamImage(ctx, variant.client, req)
+	})
+}
+
+// processImageWithProgress calls ProcessImageWithProgress on the variant,
+// passing every update to onProgress
+func (c *ImageClient) processImageWithProgress(ctx context.Context, variant *imageVariant, req *pb.ProcessImageRequest, onProgress func(*pb.ProcessImageProgress)) (*pb.ProcessImageResponse, error) {
+	return c.guardStream(ctx, "ProcessImageWithProgress", func(ctx context.Context) (*pb.ProcessImageResponse, error) {
+		stream, err := variant.client.ProcessImageWithProgress(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			update, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				return nil, status.Error(codes.Internal, "image progress stream ended without a result")
+			}
+			if err != nil {
+				return nil, err
+			}
+			if update.Result != nil {
+				return update.Result, nil
+			}
+			onProgress(update)
+		}
+	})
+}
+
+// streamImage sends metadata and the image in chunks, then collects the
+// processed image from the server's metadata and chunks
+func (c *ImageClient) streamImage(ctx context.Context, client pb.ImageServiceClient, req *pb.ProcessImageRequest) (*pb.ProcessImageResponse, error) {
+	stream, err := client.ProcessImageStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = stream.Send(&pb.ProcessImageStreamRequest{
+		Payload: &pb.ProcessImageStreamRequest_Metadata{Metadata: &pb.ProcessImageMetadata{
+			MimeType:    req.MimeType,
+			ProductName: req.ProductName,
+			Size:        int64(len(req.ImageData)),
+		}},
+	})
+	for offset := 0; err == nil && offset < len(req.ImageData); offset += c.streaming.ChunkSize {
+		end := min(offset+c.streaming.ChunkSize, len(req.ImageData))
+		err = stream.Send(&pb.ProcessImageStreamRequest{
+			Payload: &pb.ProcessImageStreamRequest_Chunk{Chunk: req.ImageData[offset:end]},
+		})
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	// Send reports io.EOF when the server ended the call; the reason
+	// comes from Recv
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return nil, err
+	}
+	metadata := first.GetMetadata()
+	if metadata == nil {
+		return nil, status.Error(codes.Internal, "image stream response did not start with metadata")
+	}
+
+	// The image must fit the receive limit it would have as one message,
+	// and must not outgrow the size the server announced
+	limit := int64(c.maxRecvSize)
+	if metadata.Size > limit {
+		return nil, status.Errorf(codes.ResourceExhausted, "image stream announces %d bytes, more than the limit of %d", metadata.Size, limit)
+	}
+	if metadata.Size > 0 {
+		limit = metadata.Size
+	}
+
+	capacity := 0
+	if metadata.Size > 0 && metadata.Size <= maxPreallocation {
+		capacity = int(metadata.Size)
+	}
+	data := make([]byte, 0, capacity)
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)+len(msg.GetChunk())) > limit {
+			if metadata.Size > 0 {
+				return nil, status.Errorf(codes.Internal, "image stream sent more than the announced %d bytes", metadata.Size)
+			}
+			return nil, status.Errorf(codes.ResourceExhausted, "image stream is larger than the limit of %d bytes", limit)
+		}
+		data = append(data, msg.GetChunk()...)
+	}
+
+	return &pb.ProcessImageResponse{
+		ProcessedImageData: data,
+		MimeType:           metadata.MimeType,
+		Message:            metadata.Message,
+	}, nil
+}
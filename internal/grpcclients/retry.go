@@ -0,0 +1,262 @@
+package grpcclients
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"stox-gateway/internal/config"
+)
+
+// retryPolicy is a parsed config.RetryPolicy
+type retryPolicy struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	multiplier     float64
+	retryable      map[codes.Code]bool
+	hedgingDelay   time.Duration
+}
+
+// parseRetryPolicies indexes the policies by short method name, e.g.
+// "ValidateToken"
+func parseRetryPolicies(policies []config.RetryPolicy) (map[string]*retryPolicy, error) {
+	byMethod := make(map[string]*retryPolicy)
+	for _, p := range policies {
+		policy := &retryPolicy{
+			maxAttempts:    p.MaxAttempts,
+			initialBackoff: p.InitialBackoff,
+			maxBackoff:     p.MaxBackoff,
+			multiplier:     p.BackoffMultiplier,
+			retryable:      make(map[codes.Code]bool),
+			hedgingDelay:   p.HedgingDelay,
+		}
+		if policy.maxAttempts < 1 {
+			policy.maxAttempts = 1
+		}
+		if policy.multiplier < 1 {
+			policy.multiplier = 1
+		}
+		if policy.maxBackoff <= 0 {
+			policy.maxBackoff = policy.initialBackoff
+		}
+
+		codeNames := p.RetryableCodes
+		if len(codeNames) == 0 {
+			codeNames = []string{"UNAVAILABLE"}
+		}
+		for _, name := range codeNames {
+			var code codes.Code
+			if err := code.UnmarshalJSON([]byte(strconv.Quote(strings.ToUpper(name)))); err != nil {
+				return nil, fmt.Errorf("invalid retryable code %q: %w", name, err)
+			}
+			policy.retryable[code] = true
+		}
+
+		for _, method := range p.Methods {
+			if _, exists := byMethod[method]; exists {
+				return nil, fmt.Errorf("method %s has more than one retry policy", method)
+			}
+			byMethod[method] = policy
+		}
+	}
+	return byMethod, nil
+}
+
+// backoff returns the jittered delay before retry number n (1-based)
+func (p *retryPolicy) backoff(n int) time.Duration {
+	ceiling := float64(p.initialBackoff) * math.Pow(p.multiplier, float64(n-1))
+	if ceiling > float64(p.maxBackoff) {
+		ceiling = float64(p.maxBackoff)
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	// Full jitter spreads retries from many callers across the window
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// shortMethod turns "/auth.AuthService/ValidateToken" into "ValidateToken"
+func shortMethod(method string) string {
+	return method[strings.LastIndex(method, "/")+1:]
+}
+
+// retryInterceptor applies the service's retry and hedging policies to unary
+// calls. Every retry and hedge is logged.
+func retryInterceptor(service string, policies []config.RetryPolicy, logger *zap.Logger) (grpc.UnaryClientInterceptor, error) {
+	byMethod, err := parseRetryPolicies(policies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry policy for %s service: %w", service, err)
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		policy, ok := byMethod[shortMethod(method)]
+		if !ok || policy.maxAttempts == 1 || isHealthCheck(method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		r := &retrier{
+			service: service,
+			method:  method,
+			policy:  policy,
+			logger:  logger,
+		}
+		if policy.hedgingDelay > 0 {
+			return r.hedge(ctx, req, reply, cc, invoker, opts...)
+		}
+		return r.retry(ctx, req, reply, cc, invoker, opts...)
+	}, nil
+}
+
+// retrier runs the attempts of one call
+type retrier struct {
+	service string
+	method  string
+	policy  *retryPolicy
+	logger  *zap.Logger
+}
+
+// retry makes sequential attempts with backoff between them
+func (r *retrier) retry(ctx context.Context, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	for attempt := 1; ; attempt++ {
+		err := invoker(ctx, r.method, req, reply, cc, opts...)
+		if err == nil || attempt >= r.policy.maxAttempts || !r.policy.retryable[status.Code(err)] {
+			return err
+		}
+
+		wait := r.policy.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			r.logger.Warn("Not retrying, deadline too close",
+				zap.String("service", r.service),
+				zap.String("method", r.method),
+				zap.Int("attempt", attempt),
+				zap.Duration("remaining", time.Until(deadline)),
+				zap.Error(err),
+			)
+			return err
+		}
+
+		r.logger.Warn("Retrying backend call",
+			zap.String("service", r.service),
+			zap.String("method", r.method),
+			zap.Int("attempt", attempt),
+			zap.String("code", status.Code(err).String()),
+			zap.Duration("backoff", wait),
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// hedgeResult is the outcome of one hedged attempt
+type hedgeResult struct {
+	attempt int
+	reply   proto.Message
+	err     error
+}
+
+// hedge starts a new attempt every hedging delay, or straight away when an
+// attempt fails with a retryable code, until one succeeds or attempts run out.
+// The first success is copied into reply and the others are cancelled.
+func (r *retrier) hedge(ctx context.Context, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	out, ok := reply.(proto.Message)
+	if !ok {
+		return r.retry(ctx, req, reply, cc, invoker, opts...)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered so attempts still running when we return never block
+	results := make(chan hedgeResult, r.policy.maxAttempts)
+	started, finished := 0, 0
+	start := func() {
+		started++
+		attempt := started
+		attemptReply := proto.Clone(out)
+		proto.Reset(attemptReply)
+		go func() {
+			err := invoker(ctx, r.method, req, attemptReply, cc, opts...)
+			results <- hedgeResult{attempt: attempt, reply: attemptReply, err: err}
+		}()
+	}
+
+	start()
+	timer := time.NewTimer(r.policy.hedgingDelay)
+	defer timer.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case <-timer.C:
+			if started < r.policy.maxAttempts {
+				r.logger.Info("Hedging backend call",
+					zap.String("service", r.service),
+					zap.String("method", r.method),
+					zap.Int("attempt", started+1),
+				)
+				start()
+				timer.Reset(r.policy.hedgingDelay)
+			}
+
+		case res := <-results:
+			finished++
+			if res.err == nil {
+				if res.attempt > 1 {
+					r.logger.Info("Hedged attempt won",
+						zap.String("service", r.service),
+						zap.String("method", r.method),
+						zap.Int("attempt", res.attempt),
+					)
+				}
+				proto.Reset(out)
+				proto.Merge(out, res.reply)
+				return nil
+			}
+
+			lastErr = res.err
+			if !r.policy.retryable[status.Code(res.err)] || ctx.Err() != nil {
+				return res.err
+			}
+			if started < r.policy.maxAttempts {
+				r.logger.Warn("Retrying backend call",
+					zap.String("service", r.service),
+					zap.String("method", r.method),
+					zap.Int("attempt", res.attempt),
+					zap.String("code", status.Code(res.err).String()),
+				)
+				start()
+				resetTimer(timer, r.policy.hedgingDelay)
+			} else if finished == started {
+				return lastErr
+			}
+		}
+	}
+}
+
+// resetTimer restarts a timer that may or may not have fired
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
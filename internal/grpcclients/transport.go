@@ -0,0 +1,103 @@
+package grpcclients
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/keepalive"
+
+	"stox-gateway/internal/config"
+)
+
+// transportOptions returns the dial options for a service's message size
+// limits, keepalive and compression settings
+func transportOptions(service string, cfg config.ServiceConfig) ([]grpc.DialOption, error) {
+	var callOpts []grpc.CallOption
+	if cfg.MaxSendMessageSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallSendMsgSize(cfg.MaxSendMessageSize))
+	}
+	if cfg.MaxRecvMessageSize > 0 {
+		callOpts = append(callOpts, grpc.MaxCallRecvMsgSize(cfg.MaxRecvMessageSize))
+	}
+	switch cfg.Compression {
+	case "", "none":
+	case gzip.Name:
+		callOpts = append(callOpts, grpc.UseCompressor(gzip.Name))
+	default:
+		return nil, fmt.Errorf("unknown compression %q for %s service", cfg.Compression, service)
+	}
+
+	var opts []grpc.DialOption
+	if len(callOpts) > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(callOpts...))
+	}
+	if cfg.Keepalive.Time > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.Keepalive.Time,
+			Timeout:             cfg.Keepalive.Timeout,
+			PermitWithoutStream: cfg.Keepalive.PermitWithoutStream,
+		}))
+	}
+	return opts, nil
+}
+
+// callTimeouts resolves the timeout of each method of a service
+type callTimeouts struct {
+	byMethod map[string]time.Duration
+	fallback time.Duration
+}
+
+func newCallTimeouts(cfg config.ServiceConfig) callTimeouts {
+	t := callTimeouts{byMethod: make(map[string]time.Duration, len(cfg.MethodTimeouts)), fallback: cfg.Timeout}
+	for _, mt := range cfg.MethodTimeouts {
+		t.byMethod[mt.Method] = mt.Timeout
+	}
+	return t
+}
+
+// forMethod returns the method's timeout, or the service timeout when the
+// method has none. Zero means no timeout.
+func (t callTimeouts) forMethod(method string) time.Duration {
+	if timeout, ok := t.byMethod[method]; ok {
+		return timeout
+	}
+	return t.fallback
+}
+
+// timeoutInterceptor bounds each call by its method timeout. It runs
+// outermost so that retries share the budget, and never extends a deadline
+// the caller already set.
+func timeoutInterceptor(cfg config.ServiceConfig) grpc.UnaryClientInterceptor {
+	timeouts := newCallTimeouts(cfg)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = withCaller(ctx)
+		if timeout := timeouts.forMethod(shortMethod(method)); timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// callerKey holds the caller's context, before our timeouts were applied
+type callerKey struct{}
+
+// withCaller remembers ctx as the caller's context, so that failures can be
+// told apart from the caller giving up
+func withCaller(ctx context.Context) context.Context {
+	return context.WithValue(ctx, callerKey{}, ctx)
+}
+
+// callerDone reports whether the caller's own context is cancelled or past
+// its deadline. Without a remembered caller ctx itself is the caller's.
+func callerDone(ctx context.Context) bool {
+	if caller, ok := ctx.Value(callerKey{}).(context.Context); ok {
+		return caller.Err() != nil
+	}
+	return ctx.Err() != nil
+}
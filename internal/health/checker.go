@@ -0,0 +1,137 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"stox-gateway/internal/config"
+)
+
+// Check statuses reported in the JSON detail view
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc checks one dependency and returns nil when it is usable
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of one dependency check. Error is logged but
+// not served, as the readiness endpoint is unauthenticated.
+type CheckResult struct {
+	Status    string `json:"status"`
+	Error     string `json:"-"`
+	LatencyMs int64  `json:"latencyMs"`
+	// Optional checks are reported without deciding readiness
+	Optional bool `json:"optional,omitempty"`
+}
+
+// Report is the combined outcome of every check
+type Report struct {
+	Status    string                 `json:"status"`
+	Checks    map[string]CheckResult `json:"checks"`
+	CheckedAt time.Time              `json:"checkedAt"`
+}
+
+// Healthy reports whether every check passed
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// namedCheck is a registered check
+type namedCheck struct {
+	name     string
+	fn       CheckFunc
+	optional bool
+}
+
+// Checker runs dependency checks concurrently and caches the report
+type Checker struct {
+	cacheTTL time.Duration
+	timeout  time.Duration
+
+	checks []namedCheck
+
+	// mu also serializes refreshes so concurrent probes share one run
+	mu     sync.Mutex
+	report *Report
+}
+
+// NewChecker creates a checker with the configured cache TTL and timeout
+func NewChecker(cfg config.HealthConfig) *Checker {
+	return &Checker{
+		cacheTTL: cfg.CacheTTL,
+		timeout:  cfg.Timeout,
+	}
+}
+
+// Register adds a named check. Call it before the checker is used.
+func (c *Checker) Register(name string, fn CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, fn: fn})
+}
+
+// RegisterOptional adds a named check whose failure is reported but leaves
+// the report healthy
+func (c *Checker) RegisterOptional(name string, fn CheckFunc) {
+	c.checks = append(c.checks, namedCheck{name: name, fn: fn, optional: true})
+}
+
+// Check returns the cached report, refreshing it once it is older than the
+// cache TTL
+func (c *Checker) Check() Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.report != nil && time.Since(c.report.CheckedAt) < c.cacheTTL {
+		return *c.report
+	}
+
+	report := c.run()
+	c.report = &report
+	return report
+}
+
+// run executes every check in parallel. Checks are detached from the probe
+// request so that a cancelled probe cannot cache a failure.
+func (c *Checker) run() Report {
+	report := Report{
+		Status:    StatusOK,
+		Checks:    make(map[string]CheckResult, len(c.checks)),
+		CheckedAt: time.Now(),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range c.checks {
+		wg.Add(1)
+		go func(check namedCheck) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.fn(ctx)
+			result := CheckResult{
+				Status:    StatusOK,
+				LatencyMs: time.Since(start).Milliseconds(),
+				Optional:  check.optional,
+			}
+			if err != nil {
+				result.Status = StatusFail
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			report.Checks[check.name] = result
+			if err != nil && !check.optional {
+				report.Status = StatusFail
+			}
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	return report
+}
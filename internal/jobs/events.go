@@ -0,0 +1,226 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"stox-gateway/internal/config"
+)
+
+// Event types
+const (
+	EventProgress  = "progress"
+	EventCompleted = "completed"
+	EventFailed    = "failed"
+)
+
+var (
+	// ErrNotFound is returned for unknown jobs and for jobs owned by someone
+	// else, so that job IDs cannot be probed
+	ErrNotFound = errors.New("job not found")
+	// ErrExists is returned when opening a job ID that is already in use
+	ErrExists = errors.New("job already exists")
+)
+
+// jobKey identifies a job. Clients may choose job IDs, so IDs are scoped to
+// their owner: the same ID used by someone else is a different job, and
+// nobody learns whether another owner's job exists.
+func jobKey(id, owner string) string {
+	return owner + "/" + id
+}
+
+// Event is one update about a job. IDs count up from 1 within a job, or from
+// the reopening time for jobs carried over from an earlier run.
+type Event struct {
+	ID   int64
+	Type string
+	Data json.RawMessage
+}
+
+// Progress is the data of a progress event
+type Progress struct {
+	Stage   string `json:"stage"`
+	Percent int    `json:"percent"`
+	Message string `json:"message,omitempty"`
+}
+
+// subscriberBuffer is the number of events a subscriber may fall behind
+// before it is dropped. A dropped subscriber resumes with Last-Event-ID.
+const subscriberBuffer = 32
+
+// Broker relays job events to subscribers and keeps recent events so that
+// subscribers can catch up after connecting or reconnecting
+type Broker struct {
+	cfg config.JobsConfig
+
+	mu      sync.Mutex
+	streams map[string]*eventStream
+}
+
+// eventStream holds the events of one job
+type eventStream struct {
+	events      []Event
+	nextID      int64
+	closed      bool
+	subscribers map[chan Event]struct{}
+}
+
+// NewBroker creates an event broker
+func NewBroker(cfg config.JobsConfig) *Broker {
+	if cfg.MaxEvents < 1 {
+		cfg.MaxEvents = 1
+	}
+	return &Broker{cfg: cfg, streams: make(map[string]*eventStream)}
+}
+
+// Open starts the event stream of a job owned by owner
+func (b *Broker) Open(jobID, owner string) error {
+	return b.open(jobID, owner, 1)
+}
+
+// Reopen starts the event stream of a job carried over from an earlier run.
+// Its IDs start at the current Unix time in milliseconds, above any ID the
+// earlier run issued, so that clients resuming with a Last-Event-ID from
+// before the restart receive the new events.
+func (b *Broker) Reopen(jobID, owner string) error {
+	return b.open(jobID, owner, time.Now().UnixMilli())
+}
+
+func (b *Broker) open(jobID, owner string, firstID int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := jobKey(jobID, owner)
+	if _, ok := b.streams[key]; ok {
+		return ErrExists
+	}
+	b.streams[key] = &eventStream{
+		nextID:      firstID,
+		subscribers: make(map[chan Event]struct{}),
+	}
+	return nil
+}
+
+// Publish records an event and sends it to the job's subscribers. Events for
+// unknown or closed jobs are dropped.
+func (b *Broker) Publish(jobID, owner, eventType string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.streams[jobKey(jobID, owner)]
+	if !ok || s.closed {
+		return nil
+	}
+
+	event := Event{ID: s.nextID, Type: eventType, Data: body}
+	s.nextID++
+	s.events = append(s.events, event)
+	if len(s.events) > b.cfg.MaxEvents {
+		s.events = s.events[len(s.events)-b.cfg.MaxEvents:]
+	}
+
+	for ch := range s.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Too far behind; the subscriber reconnects and catches up
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Close ends a job's stream after its final event. Subscribers are
+// released, and the events stay available for the retention period.
+func (b *Broker) Close(jobID, owner string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := jobKey(jobID, owner)
+	s, ok := b.streams[key]
+	if !ok || s.closed {
+		return
+	}
+	s.closed = true
+	for ch := range s.subscribers {
+		delete(s.subscribers, ch)
+		close(ch)
+	}
+
+	time.AfterFunc(b.cfg.EventRetention, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if b.streams[key] == s {
+			delete(b.streams, key)
+		}
+	})
+}
+
+// Subscription is a subscriber's view of a job's events
+type Subscription struct {
+	// Replay holds the retained events after the requested ID
+	Replay []Event
+	// Updates delivers later events. It is closed when the job ends or the
+	// subscriber falls too far behind.
+	Updates <-chan Event
+	// Ended reports that the job had already ended when subscribing
+	Ended bool
+
+	cancel func()
+}
+
+// Cancel releases the subscription
+func (s *Subscription) Cancel() {
+	s.cancel()
+}
+
+// Subscribe returns the job's retained events after afterID and its later
+// events
+func (b *Broker) Subscribe(jobID, owner string, afterID int64) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s, ok := b.streams[jobKey(jobID, owner)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	if afterID >= s.nextID {
+		// An ID this stream never issued, e.g. from before a restart whose
+		// clock went backwards; replay everything that is kept
+		afterID = 0
+	}
+
+	sub := &Subscription{Ended: s.closed, cancel: func() {}}
+	for _, event := range s.events {
+		if event.ID > afterID {
+			sub.Replay = append(sub.Replay, event)
+		}
+	}
+
+	ch := make(chan Event, subscriberBuffer)
+	sub.Updates = ch
+	if s.closed {
+		close(ch)
+		return sub, nil
+	}
+
+	s.subscribers[ch] = struct{}{}
+	sub.cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := s.subscribers[ch]; ok {
+			delete(s.subscribers, ch)
+			close(ch)
+		}
+	}
+	return sub, nil
+}
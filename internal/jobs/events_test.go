@@ -0,0 +1,174 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"stox-gateway/internal/config"
+)
+
+func TestBrokerSubscribeReplay(t *testing.T) {
+	tests := []struct {
+		name string
+		// published is the number of progress events sent before subscribing
+		published int
+		maxEvents int
+		closed    bool
+		reopened  bool
+		// seen is the number of events the client has seen; staleID, when
+		// set, is sent as Last-Event-ID instead
+		seen    int64
+		staleID int64
+		// wantReplay lists the replayed IDs, relative to the first ID
+		wantReplay []int64
+		wantEnded  bool
+	}{
+		{
+			name:       "everything from the start",
+			published:  3,
+			maxEvents:  10,
+			wantReplay: []int64{0, 1, 2},
+		},
+		{
+			name:       "after the last seen event",
+			published:  3,
+			maxEvents:  10,
+			seen:       2,
+			wantReplay: []int64{2},
+		},
+		{
+			name:      "up to date",
+			published: 3,
+			maxEvents: 10,
+			seen:      3,
+		},
+		{
+			name:       "only retained events",
+			published:  5,
+			maxEvents:  2,
+			wantReplay: []int64{3, 4},
+		},
+		{
+			name:       "ended job",
+			published:  2,
+			maxEvents:  10,
+			closed:     true,
+			seen:       1,
+			wantReplay: []int64{1},
+			wantEnded:  true,
+		},
+		{
+			name:      "ended job already seen",
+			published: 2,
+			maxEvents: 10,
+			closed:    true,
+			seen:      2,
+			wantEnded: true,
+		},
+		{
+			name:       "unissued ID replays everything",
+			published:  2,
+			maxEvents:  10,
+			staleID:    1 << 60,
+			wantReplay: []int64{0, 1},
+		},
+		{
+			name:       "reopened job after an ID from the earlier run",
+			published:  2,
+			maxEvents:  10,
+			reopened:   true,
+			staleID:    40,
+			wantReplay: []int64{0, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBroker(config.JobsConfig{MaxEvents: tt.maxEvents, EventRetention: time.Minute})
+			open := b.Open
+			if tt.reopened {
+				open = b.Reopen
+			}
+			if err := open("job-1", "alice"); err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			first := b.streams[jobKey("job-1", "alice")].nextID
+			for i := 0; i < tt.published; i++ {
+				if err := b.Publish("job-1", "alice", EventProgress, Progress{Percent: i}); err != nil {
+					t.Fatalf("Publish: %v", err)
+				}
+			}
+			if tt.closed {
+				b.Close("job-1", "alice")
+			}
+
+			afterID := tt.staleID
+			if afterID == 0 && tt.seen > 0 {
+				afterID = first + tt.seen - 1
+			}
+			sub, err := b.Subscribe("job-1", "alice", afterID)
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			defer sub.Cancel()
+
+			var got []int64
+			for _, event := range sub.Replay {
+				got = append(got, event.ID-first)
+			}
+			if len(got) != len(tt.wantReplay) {
+				t.Fatalf("replayed %v, want %v", got, tt.wantReplay)
+			}
+			for i := range got {
+				if got[i] != tt.wantReplay[i] {
+					t.Fatalf("replayed %v, want %v", got, tt.wantReplay)
+				}
+			}
+			if sub.Ended != tt.wantEnded {
+				t.Errorf("Ended = %v, want %v", sub.Ended, tt.wantEnded)
+			}
+		})
+	}
+}
+
+func TestBrokerSubscribeScopedToOwner(t *testing.T) {
+	b := NewBroker(config.JobsConfig{MaxEvents: 10})
+	if err := b.Open("job-1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Open("job-1", "bob"); err != nil {
+		t.Fatalf("Open of the same ID for another owner: %v", err)
+	}
+	if err := b.Open("job-1", "alice"); !errors.Is(err, ErrExists) {
+		t.Errorf("Open of an existing job = %v, want ErrExists", err)
+	}
+	if _, err := b.Subscribe("job-1", "carol", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Subscribe by a stranger = %v, want ErrNotFound", err)
+	}
+}
+
+func TestBrokerSubscribeReceivesUpdates(t *testing.T) {
+	b := NewBroker(config.JobsConfig{MaxEvents: 10, EventRetention: time.Minute})
+	if err := b.Open("job-1", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := b.Subscribe("job-1", "alice", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Cancel()
+
+	if err := b.Publish("job-1", "alice", EventCompleted, View{ID: "job-1"}); err != nil {
+		t.Fatal(err)
+	}
+	b.Close("job-1", "alice")
+
+	event, ok := <-sub.Updates
+	if !ok || event.Type != EventCompleted || event.ID != 1 {
+		t.Fatalf("update = %+v, %v; want the completed event with ID 1", event, ok)
+	}
+	if _, ok := <-sub.Updates; ok {
+		t.Error("Updates still open after Close")
+	}
+}
@@ -0,0 +1,445 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"stox-gateway/internal/config"
+)
+
+// ErrQueueFull is returned by Submit when QueueSize jobs are already waiting
+var ErrQueueFull = errors.New("job queue is full")
+
+// sweepInterval is how often finished jobs past their retention are removed
+const sweepInterval = time.Minute
+
+// Handler runs one attempt of a job. It may report progress through report
+// and returns the job's result, which is stored as JSON.
+type Handler func(ctx context.Context, job Job, report func(Progress)) (interface{}, error)
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks err so that the job fails without further attempts
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// View is the client-facing state of a job
+type View struct {
+	ID        string          `json:"id"`
+	Status    Status          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// View returns the job's client-facing state
+func (j *Job) View() View {
+	return View{
+		ID:        j.ID,
+		Status:    j.Status,
+		Attempts:  j.Attempts,
+		Result:    j.Result,
+		Error:     j.Error,
+		CreatedAt: j.CreatedAt,
+		UpdatedAt: j.UpdatedAt,
+	}
+}
+
+// Pool runs jobs on a bounded number of workers. Every state change is saved
+// to the store, and jobs left unfinished by a restart are queued again when
+// the pool is created. The store is local to the process, so a job is only
+// known to the gateway that accepted it.
+type Pool struct {
+	cfg    config.JobsConfig
+	store  Store
+	events *Broker
+	logger *zap.Logger
+
+	// finished is called with each job that reaches a final state
+	finished func(Job)
+
+	mu sync.Mutex
+	// jobs and pending are keyed by jobKey
+	jobs    map[string]*Job
+	pending []string
+	wake    chan struct{}
+}
+
+// NewPool creates a pool and loads the jobs kept in store
+func NewPool(cfg config.JobsConfig, store Store, events *Broker, logger *zap.Logger) (*Pool, error) {
+	if cfg.Workers < 1 {
+		cfg.Workers = 1
+	}
+	if cfg.QueueSize < 1 {
+		cfg.QueueSize = 1
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+
+	p := &Pool{
+		cfg:    cfg,
+		store:  store,
+		events: events,
+		logger: logger,
+		jobs:   make(map[string]*Job),
+		wake:   make(chan struct{}, 1),
+	}
+
+	stored, skipped, err := store.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load jobs: %w", err)
+	}
+	for _, err := range skipped {
+		logger.Warn("Skipped unreadable stored job", zap.Error(err))
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].CreatedAt.Before(stored[j].CreatedAt)
+	})
+
+	now := time.Now()
+	for _, job := range stored {
+		if job.Status.Finished() {
+			if now.Sub(job.UpdatedAt) > cfg.Retention {
+				if err := store.Delete(job); err != nil {
+					logger.Warn("Failed to delete expired job", zap.String("jobID", job.ID), zap.Error(err))
+				}
+				continue
+			}
+			p.jobs[jobKey(job.ID, job.Owner)] = job
+			// Clients that reconnect get the final event they may have
+			// missed, then are told the job has ended
+			if err := events.Reopen(job.ID, job.Owner); err != nil {
+				logger.Warn("Failed to open events of finished job", zap.String("jobID", job.ID), zap.Error(err))
+			}
+			p.publish(job, finalEvent(job.Status), job.View())
+			events.Close(job.ID, job.Owner)
+			continue
+		}
+
+		// Interrupted by a restart
+		job.Status = StatusQueued
+		key := jobKey(job.ID, job.Owner)
+		p.jobs[key] = job
+		p.pending = append(p.pending, key)
+		if err := events.Reopen(job.ID, job.Owner); err != nil {
+			logger.Warn("Failed to open events of resumed job", zap.String("jobID", job.ID), zap.Error(err))
+		}
+	}
+	if len(p.pending) > 0 {
+		logger.Info("Resuming unfinished jobs", zap.Int("count", len(p.pending)))
+	}
+	return p, nil
+}
+
+// Submit queues a new job with the given ID for owner. IDs are scoped to
+// their owner; ErrExists means owner already has a job with this ID.
+func (p *Pool) Submit(id, owner string, payload interface{}) (View, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return View{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := jobKey(id, owner)
+	if _, ok := p.jobs[key]; ok {
+		return View{}, ErrExists
+	}
+	if len(p.pending) >= p.cfg.QueueSize {
+		return View{}, ErrQueueFull
+	}
+	if err := p.events.Open(id, owner); err != nil {
+		return View{}, err
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:        id,
+		Owner:     owner,
+		Status:    StatusQueued,
+		Payload:   body,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := p.store.Save(job); err != nil {
+		p.events.Close(id, owner)
+		return View{}, fmt.Errorf("failed to save job: %w", err)
+	}
+
+	p.jobs[key] = job
+	p.pending = append(p.pending, key)
+	p.signal()
+	p.publish(job, EventProgress, Progress{Stage: "queued"})
+	return job.View(), nil
+}
+
+// Get returns the job with the given ID if owner owns it
+func (p *Pool) Get(id, owner string) (View, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	job, ok := p.jobs[jobKey(id, owner)]
+	if !ok {
+		return View{}, ErrNotFound
+	}
+	return job.View(), nil
+}
+
+// OnFinish registers fn to be called with each job that succeeds or fails
+// for good. Call it before Run.
+func (p *Pool) OnFinish(fn func(job Job)) {
+	p.finished = fn
+}
+
+// Run starts the workers and blocks until they stop. Workers stop taking jobs
+// once draining closes and abandon running jobs when ctx is cancelled;
+// abandoned jobs are saved as queued and resume on the next start.
+func (p *Pool) Run(ctx context.Context, draining <-chan struct{}, handler Handler) {
+	var wg sync.WaitGroup
+	for i := 0; i < p.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				job := p.next(ctx, draining)
+				if job == nil {
+					return
+				}
+				p.run(ctx, draining, handler, job)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for done := false; !done; {
+		select {
+		case <-ticker.C:
+			p.sweep()
+		case <-draining:
+			done = true
+		case <-ctx.Done():
+			done = true
+		}
+	}
+	wg.Wait()
+}
+
+// signal wakes a waiting worker. Callers hold p.mu.
+func (p *Pool) signal() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// next takes the oldest queued job, waiting for one if none is queued. It
+// returns nil when the pool is stopping.
+func (p *Pool) next(ctx context.Context, draining <-chan struct{}) *Job {
+	for {
+		select {
+		case <-draining:
+			return nil
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		p.mu.Lock()
+		if len(p.pending) > 0 {
+			job := p.jobs[p.pending[0]]
+			p.pending = p.pending[1:]
+			if len(p.pending) > 0 {
+				p.signal()
+			}
+			p.mu.Unlock()
+			return job
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-p.wake:
+		case <-draining:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// run attempts a job until it succeeds, fails permanently, runs out of
+// attempts or the pool stops
+func (p *Pool) run(ctx context.Context, draining <-chan struct{}, handler Handler, job *Job) {
+	report := func(progress Progress) {
+		p.publish(job, EventProgress, progress)
+	}
+
+	for {
+		snapshot := p.update(job, func(j *Job) {
+			j.Status = StatusRunning
+			j.Attempts++
+		})
+		p.publish(job, EventProgress, Progress{
+			Stage:   "started",
+			Message: fmt.Sprintf("Attempt %d of %d", snapshot.Attempts, p.cfg.MaxAttempts),
+		})
+
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if p.cfg.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, p.cfg.Timeout)
+		}
+		result, err := p.attempt(attemptCtx, handler, snapshot, report)
+		cancel()
+
+		if err == nil {
+			body, err := json.Marshal(result)
+			if err == nil {
+				p.finish(job, StatusSucceeded, body, "")
+				return
+			}
+			p.finish(job, StatusFailed, nil, "failed to encode job result")
+			return
+		}
+
+		if ctx.Err() != nil {
+			// The pool is shutting down; the attempt doesn't count
+			p.update(job, func(j *Job) {
+				j.Status = StatusQueued
+				j.Attempts--
+			})
+			p.logger.Info("Job interrupted by shutdown, will resume on restart", zap.String("jobID", job.ID))
+			return
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || snapshot.Attempts >= p.cfg.MaxAttempts {
+			p.logger.Error("Job failed", zap.String("jobID", job.ID), zap.Int("attempts", snapshot.Attempts), zap.Error(err))
+			p.finish(job, StatusFailed, nil, err.Error())
+			return
+		}
+
+		backoff := p.cfg.RetryBackoff << (snapshot.Attempts - 1)
+		p.logger.Warn("Job attempt failed, retrying",
+			zap.String("jobID", job.ID),
+			zap.Int("attempt", snapshot.Attempts),
+			zap.Duration("backoff", backoff),
+			zap.Error(err),
+		)
+		p.update(job, func(j *Job) {
+			j.Status = StatusQueued
+			j.Error = err.Error()
+		})
+		p.publish(job, EventProgress, Progress{Stage: "retrying", Message: err.Error()})
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-draining:
+			timer.Stop()
+			return
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// attempt runs the handler, turning a panic into a permanent failure
+func (p *Pool) attempt(ctx context.Context, handler Handler, job Job, report func(Progress)) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Error("Job handler panicked", zap.String("jobID", job.ID), zap.Any("panic", r))
+			err = Permanent(errors.New("internal error"))
+		}
+	}()
+	return handler(ctx, job, report)
+}
+
+// update changes a job, saves it and returns a copy
+func (p *Pool) update(job *Job, change func(*Job)) Job {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	change(job)
+	job.UpdatedAt = time.Now()
+	if err := p.store.Save(job); err != nil {
+		p.logger.Error("Failed to save job", zap.String("jobID", job.ID), zap.Error(err))
+	}
+	return *job
+}
+
+// finish records a job's final state and ends its event stream
+func (p *Pool) finish(job *Job, status Status, result json.RawMessage, errMessage string) {
+	snapshot := p.update(job, func(j *Job) {
+		j.Status = status
+		j.Result = result
+		j.Error = errMessage
+	})
+
+	p.publish(job, finalEvent(status), snapshot.View())
+	p.events.Close(job.ID, job.Owner)
+
+	if p.finished != nil {
+		p.finished(snapshot)
+	}
+}
+
+// finalEvent returns the type of the event that ends a job with status
+func finalEvent(status Status) string {
+	if status == StatusFailed {
+		return EventFailed
+	}
+	return EventCompleted
+}
+
+// publish sends a job event, logging failures. A job's ID and owner never
+// change, so they are read without p.mu.
+func (p *Pool) publish(job *Job, eventType string, data interface{}) {
+	if err := p.events.Publish(job.ID, job.Owner, eventType, data); err != nil {
+		p.logger.Warn("Failed to publish job event", zap.String("jobID", job.ID), zap.Error(err))
+	}
+}
+
+// sweep removes finished jobs whose retention has expired
+func (p *Pool) sweep() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for key, job := range p.jobs {
+		if !job.Status.Finished() || now.Sub(job.UpdatedAt) <= p.cfg.Retention {
+			continue
+		}
+		if err := p.store.Delete(job); err != nil {
+			p.logger.Warn("Failed to delete expired job", zap.String("jobID", job.ID), zap.Error(err))
+			continue
+		}
+		delete(p.jobs, key)
+	}
+}
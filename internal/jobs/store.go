@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Status is the state of a job
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Finished reports whether the job has reached a final state
+func (s Status) Finished() bool {
+	return s == StatusSucceeded || s == StatusFailed
+}
+
+// Job is a unit of background work. Payload holds the job's input and Result
+// its output, both as JSON chosen by the job's handler.
+type Job struct {
+	ID        string          `json:"id"`
+	Owner     string          `json:"owner"`
+	Status    Status          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	Payload   json.RawMessage `json:"payload"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+	UpdatedAt time.Time       `json:"updatedAt"`
+}
+
+// Store persists jobs so that they survive a restart
+type Store interface {
+	Save(job *Job) error
+	List() (jobs []*Job, skipped []error, err error)
+	Delete(job *Job) error
+}
+
+// FileStore keeps each job in its own JSON file. Files are replaced
+// atomically, so a crash leaves either the old or the new state.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path names a job's file after its owner and ID, since job IDs are only
+// unique per owner. The owner is hashed to keep the name safe.
+func (s *FileStore) path(job *Job) string {
+	owner := sha256.Sum256([]byte(job.Owner))
+	return filepath.Join(s.dir, hex.EncodeToString(owner[:8])+"-"+job.ID+".json")
+}
+
+// Save writes the job's current state
+func (s *FileStore) Save(job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".job-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(job))
+}
+
+// quarantineDir is the subdirectory unreadable job files are moved to, so
+// that they are kept for inspection without failing every start
+const quarantineDir = "quarantine"
+
+// List reads every stored job. Unreadable files are moved to the quarantine
+// directory and reported in skipped; err is only set when the store itself
+// cannot be read.
+func (s *FileStore) List() (jobs []*Job, skipped []error, err error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		if err == nil {
+			job := &Job{}
+			if err = json.Unmarshal(data, job); err == nil {
+				jobs = append(jobs, job)
+				continue
+			}
+		}
+		skipped = append(skipped, fmt.Errorf("%s: %w", name, err))
+		if err := s.quarantine(name); err != nil {
+			skipped = append(skipped, err)
+		}
+	}
+	return jobs, skipped, nil
+}
+
+// quarantine moves an unreadable job file out of the store
+func (s *FileStore) quarantine(name string) error {
+	dir := filepath.Join(s.dir, quarantineDir)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	if err := os.Rename(filepath.Join(s.dir, name), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("failed to quarantine %s: %w", name, err)
+	}
+	return nil
+}
+
+// Delete removes a job
+func (s *FileStore) Delete(job *Job) error {
+	if err := os.Remove(s.path(job)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package lifecycle
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"stox-gateway/internal/config"
+)
+
+// closer is a named resource released at the end of shutdown
+type closer struct {
+	name string
+	fn   func() error
+}
+
+// Manager coordinates graceful shutdown: it owns readiness, tracks background
+// tasks that outlive the request that started them, and closes resources in
+// order once everything has drained.
+type Manager struct {
+	cfg    config.ShutdownConfig
+	logger *zap.Logger
+
+	ready atomic.Bool
+
+	// draining is closed once the server has stopped taking requests
+	draining chan struct{}
+
+	// tasksCtx is handed to background tasks and cancelled when they run out
+	// of time during shutdown
+	tasksCtx    context.Context
+	cancelTasks context.CancelFunc
+
+	// tasksMu guards the task count. Tasks may start other tasks while
+	// shutdown waits; once none are left, closed rejects new ones. A
+	// WaitGroup can't do this, as Add from zero must not race with Wait.
+	tasksMu sync.Mutex
+	running int
+	closed  bool
+	// idle is closed when the last task finishes during shutdown
+	idle chan struct{}
+
+	mu      sync.Mutex
+	closers []closer
+}
+
+// NewManager creates a lifecycle manager. The gateway reports ready until
+// Shutdown is called.
+func NewManager(cfg config.ShutdownConfig, logger *zap.Logger) *Manager {
+	tasksCtx, cancelTasks := context.WithCancel(context.Background())
+	m := &Manager{
+		cfg:         cfg,
+		logger:      logger,
+		tasksCtx:    tasksCtx,
+		cancelTasks: cancelTasks,
+		draining:    make(chan struct{}),
+	}
+	m.ready.Store(true)
+	return m
+}
+
+// Ready reports whether the gateway should receive new traffic
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// Draining is closed once the server has stopped during shutdown. Background
+// tasks that take work from a queue stop taking more when it closes.
+func (m *Manager) Draining() <-chan struct{} {
+	return m.draining
+}
+
+// Go runs fn in the background and waits for it during shutdown. The context
+// is detached from any request and is cancelled once the background timeout
+// expires. Once shutdown has finished waiting, fn is dropped and logged.
+func (m *Manager) Go(name string, fn func(ctx context.Context)) {
+	m.tasksMu.Lock()
+	if m.closed {
+		m.tasksMu.Unlock()
+		m.logger.Warn("Background task started after shutdown, dropping", zap.String("task", name))
+		return
+	}
+	m.running++
+	m.tasksMu.Unlock()
+
+	go func() {
+		defer m.taskDone()
+		defer func() {
+			if r := recover(); r != nil {
+				m.logger.Error("Background task panicked", zap.String("task", name), zap.Any("panic", r))
+			}
+		}()
+		fn(m.tasksCtx)
+	}()
+}
+
+// taskDone counts a finished task, closing idle after the last one
+func (m *Manager) taskDone() {
+	m.tasksMu.Lock()
+	defer m.tasksMu.Unlock()
+	m.running--
+	if m.running == 0 && m.idle != nil {
+		m.closed = true
+		close(m.idle)
+		m.idle = nil
+	}
+}
+
+// OnClose registers a resource to release after the server and background
+// tasks have stopped. Closers run in reverse order of registration, like
+// deferred calls, so register a dependency before the things that use it.
+func (m *Manager) OnClose(name string, fn func() error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closers = append(m.closers, closer{name: name, fn: fn})
+}
+
+// Shutdown drains the gateway: readiness fails, then after the drain delay
+// the server stops and in-flight requests finish, then background tasks are
+// waited on, then resources are closed and the logger is synced.
+func (m *Manager) Shutdown(server *http.Server) {
+	m.ready.Store(false)
+	m.logger.Info("Readiness set to failing, draining", zap.Duration("drainDelay", m.cfg.DrainDelay))
+
+	// Give load balancers time to notice before connections are refused
+	time.Sleep(m.cfg.DrainDelay)
+
+	m.shutdownServer(server)
+	close(m.draining)
+	m.waitForTasks()
+	m.runClosers()
+
+	m.logger.Info("Shutdown complete")
+	_ = m.logger.Sync()
+}
+
+// shutdownServer stops the server, forcing connections closed if in-flight
+// requests outlast the shutdown timeout
+func (m *Manager) shutdownServer(server *http.Server) {
+	m.logger.Info("Shutting down server...", zap.Duration("timeout", m.cfg.Timeout))
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.cfg.Timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		m.logger.Error("Server did not drain in time, closing connections", zap.Error(err))
+		if err := server.Close(); err != nil {
+			m.logger.Error("Failed to close server", zap.Error(err))
+		}
+		return
+	}
+	m.logger.Info("Server stopped")
+}
+
+// waitForTasks waits for background tasks, cancelling them at the deadline
+func (m *Manager) waitForTasks() {
+	done := make(chan struct{})
+	m.tasksMu.Lock()
+	if m.running == 0 {
+		m.closed = true
+		close(done)
+	} else {
+		m.idle = done
+	}
+	m.tasksMu.Unlock()
+
+	select {
+	case <-done:
+		m.logger.Info("Background tasks finished")
+	case <-time.After(m.cfg.BackgroundTimeout):
+		m.logger.Warn("Background tasks did not finish in time, cancelling",
+			zap.Duration("timeout", m.cfg.BackgroundTimeout),
+		)
+		m.cancelTasks()
+		// Cancellation is cooperative; allow a moment for tasks to unwind
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			m.logger.Warn("Abandoning background tasks that ignored cancellation")
+		}
+	}
+	m.tasksMu.Lock()
+	m.closed = true
+	m.idle = nil
+	m.tasksMu.Unlock()
+	m.cancelTasks()
+}
+
+// runClosers releases registered resources in reverse order
+func (m *Manager) runClosers() {
+	m.mu.Lock()
+	closers := m.closers
+	m.closers = nil
+	m.mu.Unlock()
+
+	for i := len(closers) - 1; i >= 0; i-- {
+		c := closers[i]
+		if err := c.fn(); err != nil {
+			m.logger.Error("Failed to close resource", zap.String("resource", c.name), zap.Error(err))
+			continue
+		}
+		m.logger.Debug("Closed resource", zap.String("resource", c.name))
+	}
+}
@@ -0,0 +1,58 @@
+package lifecycle
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"stox-gateway/internal/config"
+)
+
+func TestWaitForTasks(t *testing.T) {
+	tests := []struct {
+		name  string
+		tasks int
+		// nested is the number of tasks each task starts before finishing
+		nested  int
+		wantRan int32
+	}{
+		{name: "no tasks"},
+		{name: "tasks", tasks: 3, wantRan: 3},
+		{name: "tasks starting tasks while waiting", tasks: 2, nested: 2, wantRan: 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManager(config.ShutdownConfig{BackgroundTimeout: 5 * time.Second}, zap.NewNop())
+			var ran atomic.Int32
+			release := make(chan struct{})
+			for i := 0; i < tt.tasks; i++ {
+				m.Go("task", func(context.Context) {
+					<-release
+					for j := 0; j < tt.nested; j++ {
+						m.Go("nested", func(context.Context) {
+							time.Sleep(10 * time.Millisecond)
+							ran.Add(1)
+						})
+					}
+					ran.Add(1)
+				})
+			}
+
+			close(release)
+			m.waitForTasks()
+			if got := ran.Load(); got != tt.wantRan {
+				t.Errorf("ran %d tasks, want %d", got, tt.wantRan)
+			}
+
+			m.Go("late", func(context.Context) { ran.Add(1) })
+			time.Sleep(10 * time.Millisecond)
+			if got := ran.Load(); got != tt.wantRan {
+				t.Errorf("task started after shutdown ran")
+			}
+		})
+	}
+}
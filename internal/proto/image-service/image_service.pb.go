@@ -143,6 +143,363 @@ func (x *ProcessImageResponse) GetMessage() string {
 	return ""
 }
 
+// Describes the image sent in a ProcessImageStream call
+type ProcessImageMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MimeType      string                 `protobuf:"bytes,1,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"`          // MIME type of the image
+	ProductName   string                 `protobuf:"bytes,2,opt,name=product_name,json=productName,proto3" json:"product_name,omitempty"` // Optional product name for context
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`                                 // Total image size in bytes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessImageMetadata) Reset() {
+	*x = ProcessImageMetadata{}
+	mi := &file_internal_proto_image_service_image_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessImageMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessImageMetadata) ProtoMessage() {}
+
+func (x *ProcessImageMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_image_service_image_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessImageMetadata.ProtoReflect.Descriptor instead.
+func (*ProcessImageMetadata) Descriptor() ([]byte, []int) {
+	return file_internal_proto_image_service_image_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ProcessImageMetadata) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *ProcessImageMetadata) GetProductName() string {
+	if x != nil {
+		return x.ProductName
+	}
+	return ""
+}
+
+func (x *ProcessImageMetadata) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+// Describes the processed image returned by a ProcessImageStream call
+type ProcessedImageMetadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MimeType      string                 `protobuf:"bytes,1,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"` // MIME type of the processed image
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`                   // Optional message or status
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`                        // Total processed image size in bytes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessedImageMetadata) Reset() {
+	*x = ProcessedImageMetadata{}
+	mi := &file_internal_proto_image_service_image_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessedImageMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessedImageMetadata) ProtoMessage() {}
+
+func (x *ProcessedImageMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_image_service_image_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessedImageMetadata.ProtoReflect.Descriptor instead.
+func (*ProcessedImageMetadata) Descriptor() ([]byte, []int) {
+	return file_internal_proto_image_service_image_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ProcessedImageMetadata) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *ProcessedImageMetadata) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ProcessedImageMetadata) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+// Client message of ProcessImageStream: metadata first, then chunks
+type ProcessImageStreamRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*ProcessImageStreamRequest_Metadata
+	//	*ProcessImageStreamRequest_Chunk
+	Payload       isProcessImageStreamRequest_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessImageStreamRequest) Reset() {
+	*x = ProcessImageStreamRequest{}
+	mi := &file_internal_proto_image_service_image_service_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessImageStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessImageStreamRequest) ProtoMessage() {}
+
+func (x *ProcessImageStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_image_service_image_service_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessImageStreamRequest.ProtoReflect.Descriptor instead.
+func (*ProcessImageStreamRequest) Descriptor() ([]byte, []int) {
+	return file_internal_proto_image_service_image_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ProcessImageStreamRequest) GetPayload() isProcessImageStreamRequest_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *ProcessImageStreamRequest) GetMetadata() *ProcessImageMetadata {
+	if x != nil {
+		if x, ok := x.Payload.(*ProcessImageStreamRequest_Metadata); ok {
+			return x.Metadata
+		}
+	}
+	return nil
+}
+
+func (x *ProcessImageStreamRequest) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*ProcessImageStreamRequest_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isProcessImageStreamRequest_Payload interface {
+	isProcessImageStreamRequest_Payload()
+}
+
+type ProcessImageStreamRequest_Metadata struct {
+	Metadata *ProcessImageMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"`
+}
+
+type ProcessImageStreamRequest_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"` // Next part of the image
+}
+
+func (*ProcessImageStreamRequest_Metadata) isProcessImageStreamRequest_Payload() {}
+
+func (*ProcessImageStreamRequest_Chunk) isProcessImageStreamRequest_Payload() {}
+
+// Server message of ProcessImageStream: metadata first, then chunks
+type ProcessImageStreamResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*ProcessImageStreamResponse_Metadata
+	//	*ProcessImageStreamResponse_Chunk
+	Payload       isProcessImageStreamResponse_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessImageStreamResponse) Reset() {
+	*x = ProcessImageStreamResponse{}
+	mi := &file_internal_proto_image_service_image_service_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessImageStreamResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessImageStreamResponse) ProtoMessage() {}
+
+func (x *ProcessImageStreamResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_image_service_image_service_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessImageStreamResponse.ProtoReflect.Descriptor instead.
+func (*ProcessImageStreamResponse) Descriptor() ([]byte, []int) {
+	return file_internal_proto_image_service_image_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ProcessImageStreamResponse) GetPayload() isProcessImageStreamResponse_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *ProcessImageStreamResponse) GetMetadata() *ProcessedImageMetadata {
+	if x != nil {
+		if x, ok := x.Payload.(*ProcessImageStreamResponse_Metadata); ok {
+			return x.Metadata
+		}
+	}
+	return nil
+}
+
+func (x *ProcessImageStreamResponse) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Payload.(*ProcessImageStreamResponse_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isProcessImageStreamResponse_Payload interface {
+	isProcessImageStreamResponse_Payload()
+}
+
+type ProcessImageStreamResponse_Metadata struct {
+	Metadata *ProcessedImageMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"`
+}
+
+type ProcessImageStreamResponse_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"` // Next part of the processed image
+}
+
+func (*ProcessImageStreamResponse_Metadata) isProcessImageStreamResponse_Payload() {}
+
+func (*ProcessImageStreamResponse_Chunk) isProcessImageStreamResponse_Payload() {}
+
+// Progress update of a ProcessImageWithProgress call
+type ProcessImageProgress struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Stage         string                 `protobuf:"bytes,1,opt,name=stage,proto3" json:"stage,omitempty"`      // Current stage, e.g. "analyzing" or "enhancing"
+	Percent       int32                  `protobuf:"varint,2,opt,name=percent,proto3" json:"percent,omitempty"` // Overall completion, 0 to 100
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`  // Optional human-readable detail
+	Result        *ProcessImageResponse  `protobuf:"bytes,4,opt,name=result,proto3" json:"result,omitempty"`    // Set on the final message only
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProcessImageProgress) Reset() {
+	*x = ProcessImageProgress{}
+	mi := &file_internal_proto_image_service_image_service_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProcessImageProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProcessImageProgress) ProtoMessage() {}
+
+func (x *ProcessImageProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_internal_proto_image_service_image_service_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProcessImageProgress.ProtoReflect.Descriptor instead.
+func (*ProcessImageProgress) Descriptor() ([]byte, []int) {
+	return file_internal_proto_image_service_image_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ProcessImageProgress) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *ProcessImageProgress) GetPercent() int32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *ProcessImageProgress) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ProcessImageProgress) GetResult() *ProcessImageResponse {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
 var File_internal_proto_image_service_image_service_proto protoreflect.FileDescriptor
 
 const file_internal_proto_image_service_image_service_proto_rawDesc = "" +
@@ -156,9 +513,32 @@ const file_internal_proto_image_service_image_service_proto_rawDesc = "" +
 	"\x14ProcessImageResponse\x120\n" +
 	"\x14processed_image_data\x18\x01 \x01(\fR\x12processedImageData\x12\x1b\n" +
 	"\tmime_type\x18\x02 \x01(\tR\bmimeType\x12\x18\n" +
-	"\amessage\x18\x03 \x01(\tR\amessage2e\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"j\n" +
+	"\x14ProcessImageMetadata\x12\x1b\n" +
+	"\tmime_type\x18\x01 \x01(\tR\bmimeType\x12!\n" +
+	"\fproduct_name\x18\x02 \x01(\tR\vproductName\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\"c\n" +
+	"\x16ProcessedImageMetadata\x12\x1b\n" +
+	"\tmime_type\x18\x01 \x01(\tR\bmimeType\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\"\x80\x01\n" +
+	"\x19ProcessImageStreamRequest\x12@\n" +
+	"\bmetadata\x18\x01 \x01(\v2\".imageservice.ProcessImageMetadataH\x00R\bmetadata\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\t\n" +
+	"\apayload\"\x83\x01\n" +
+	"\x1aProcessImageStreamResponse\x12B\n" +
+	"\bmetadata\x18\x01 \x01(\v2$.imageservice.ProcessedImageMetadataH\x00R\bmetadata\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\t\n" +
+	"\apayload\"\x9c\x01\n" +
+	"\x14ProcessImageProgress\x12\x14\n" +
+	"\x05stage\x18\x01 \x01(\tR\x05stage\x12\x18\n" +
+	"\apercent\x18\x02 \x01(\x05R\apercent\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12:\n" +
+	"\x06result\x18\x04 \x01(\v2\".imageservice.ProcessImageResponseR\x06result2\xb7\x02\n" +
 	"\fImageService\x12U\n" +
-	"\fProcessImage\x12!.imageservice.ProcessImageRequest\x1a\".imageservice.ProcessImageResponseB+Z)stox-gateway/internal/proto/image-serviceb\x06proto3"
+	"\fProcessImage\x12!.imageservice.ProcessImageRequest\x1a\".imageservice.ProcessImageResponse\x12k\n" +
+	"\x12ProcessImageStream\x12'.imageservice.ProcessImageStreamRequest\x1a(.imageservice.ProcessImageStreamResponse(\x010\x01\x12c\n" +
+	"\x18ProcessImageWithProgress\x12!.imageservice.ProcessImageRequest\x1a\".imageservice.ProcessImageProgress0\x01B+Z)stox-gateway/internal/proto/image-serviceb\x06proto3"
 
 var (
 	file_internal_proto_image_service_image_service_proto_rawDescOnce sync.Once
@@ -172,19 +552,31 @@ func file_internal_proto_image_service_image_service_proto_rawDescGZIP() []byte
 	return file_internal_proto_image_service_image_service_proto_rawDescData
 }
 
-var file_internal_proto_image_service_image_service_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_internal_proto_image_service_image_service_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_internal_proto_image_service_image_service_proto_goTypes = []any{
-	(*ProcessImageRequest)(nil),  // 0: imageservice.ProcessImageRequest
-	(*ProcessImageResponse)(nil), // 1: imageservice.ProcessImageResponse
+	(*ProcessImageRequest)(nil),        // 0: imageservice.ProcessImageRequest
+	(*ProcessImageResponse)(nil),       // 1: imageservice.ProcessImageResponse
+	(*ProcessImageMetadata)(nil),       // 2: imageservice.ProcessImageMetadata
+	(*ProcessedImageMetadata)(nil),     // 3: imageservice.ProcessedImageMetadata
+	(*ProcessImageStreamRequest)(nil),  // 4: imageservice.ProcessImageStreamRequest
+	(*ProcessImageStreamResponse)(nil), // 5: imageservice.ProcessImageStreamResponse
+	(*ProcessImageProgress)(nil),       // 6: imageservice.ProcessImageProgress
 }
 var file_internal_proto_image_service_image_service_proto_depIdxs = []int32{
-	0, // 0: imageservice.ImageService.ProcessImage:input_type -> imageservice.ProcessImageRequest
-	1, // 1: imageservice.ImageService.ProcessImage:output_type -> imageservice.ProcessImageResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	2, // 0: imageservice.ProcessImageStreamRequest.metadata:type_name -> imageservice.ProcessImageMetadata
+	3, // 1: imageservice.ProcessImageStreamResponse.metadata:type_name -> imageservice.ProcessedImageMetadata
+	1, // 2: imageservice.ProcessImageProgress.result:type_name -> imageservice.ProcessImageResponse
+	0, // 3: imageservice.ImageService.ProcessImage:input_type -> imageservice.ProcessImageRequest
+	4, // 4: imageservice.ImageService.ProcessImageStream:input_type -> imageservice.ProcessImageStreamRequest
+	0, // 5: imageservice.ImageService.ProcessImageWithProgress:input_type -> imageservice.ProcessImageRequest
+	1, // 6: imageservice.ImageService.ProcessImage:output_type -> imageservice.ProcessImageResponse
+	5, // 7: imageservice.ImageService.ProcessImageStream:output_type -> imageservice.ProcessImageStreamResponse
+	6, // 8: imageservice.ImageService.ProcessImageWithProgress:output_type -> imageservice.ProcessImageProgress
+	6, // [6:9] is the sub-list for method output_type
+	3, // [3:6] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
 }
 
 func init() { file_internal_proto_image_service_image_service_proto_init() }
@@ -192,13 +584,21 @@ func file_internal_proto_image_service_image_service_proto_init() {
 	if File_internal_proto_image_service_image_service_proto != nil {
 		return
 	}
+	file_internal_proto_image_service_image_service_proto_msgTypes[4].OneofWrappers = []any{
+		(*ProcessImageStreamRequest_Metadata)(nil),
+		(*ProcessImageStreamRequest_Chunk)(nil),
+	}
+	file_internal_proto_image_service_image_service_proto_msgTypes[5].OneofWrappers = []any{
+		(*ProcessImageStreamResponse_Metadata)(nil),
+		(*ProcessImageStreamResponse_Chunk)(nil),
+	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_internal_proto_image_service_image_service_proto_rawDesc), len(file_internal_proto_image_service_image_service_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
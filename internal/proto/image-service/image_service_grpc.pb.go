@@ -19,7 +19,9 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	ImageService_ProcessImage_FullMethodName = "/imageservice.ImageService/ProcessImage"
+	ImageService_ProcessImage_FullMethodName             = "/imageservice.ImageService/ProcessImage"
+	ImageService_ProcessImageStream_FullMethodName       = "/imageservice.ImageService/ProcessImageStream"
+	ImageService_ProcessImageWithProgress_FullMethodName = "/imageservice.ImageService/ProcessImageWithProgress"
 )
 
 // ImageServiceClient is the client API for ImageService service.
@@ -30,6 +32,13 @@ const (
 type ImageServiceClient interface {
 	// Process an image and return the processed image
 	ProcessImage(ctx context.Context, in *ProcessImageRequest, opts ...grpc.CallOption) (*ProcessImageResponse, error)
+	// Process a large image sent in chunks. The client sends metadata followed
+	// by image chunks and closes its side; the server answers with metadata
+	// followed by processed image chunks.
+	ProcessImageStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ProcessImageStreamRequest, ProcessImageStreamResponse], error)
+	// Process an image, reporting progress while it runs. The last message
+	// carries the result.
+	ProcessImageWithProgress(ctx context.Context, in *ProcessImageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProcessImageProgress], error)
 }
 
 type imageServiceClient struct {
@@ -50,6 +59,38 @@ func (c *imageServiceClient) ProcessImage(ctx context.Context, in *ProcessImageR
 	return out, nil
 }
 
+func (c *imageServiceClient) ProcessImageStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ProcessImageStreamRequest, ProcessImageStreamResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ImageService_ServiceDesc.Streams[0], ImageService_ProcessImageStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ProcessImageStreamRequest, ProcessImageStreamResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ImageService_ProcessImageStreamClient = grpc.BidiStreamingClient[ProcessImageStreamRequest, ProcessImageStreamResponse]
+
+func (c *imageServiceClient) ProcessImageWithProgress(ctx context.Context, in *ProcessImageRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ProcessImageProgress], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ImageService_ServiceDesc.Streams[1], ImageService_ProcessImageWithProgress_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ProcessImageRequest, ProcessImageProgress]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ImageService_ProcessImageWithProgressClient = grpc.ServerStreamingClient[ProcessImageProgress]
+
 // ImageServiceServer is the server API for ImageService service.
 // All implementations must embed UnimplementedImageServiceServer
 // for forward compatibility.
@@ -58,6 +99,13 @@ func (c *imageServiceClient) ProcessImage(ctx context.Context, in *ProcessImageR
 type ImageServiceServer interface {
 	// Process an image and return the processed image
 	ProcessImage(context.Context, *ProcessImageRequest) (*ProcessImageResponse, error)
+	// Process a large image sent in chunks. The client sends metadata followed
+	// by image chunks and closes its side; the server answers with metadata
+	// followed by processed image chunks.
+	ProcessImageStream(grpc.BidiStreamingServer[ProcessImageStreamRequest, ProcessImageStreamResponse]) error
+	// Process an image, reporting progress while it runs. The last message
+	// carries the result.
+	ProcessImageWithProgress(*ProcessImageRequest, grpc.ServerStreamingServer[ProcessImageProgress]) error
 	mustEmbedUnimplementedImageServiceServer()
 }
 
@@ -71,6 +119,12 @@ type UnimplementedImageServiceServer struct{}
 func (UnimplementedImageServiceServer) ProcessImage(context.Context, *ProcessImageRequest) (*ProcessImageResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ProcessImage not implemented")
 }
+func (UnimplementedImageServiceServer) ProcessImageStream(grpc.BidiStreamingServer[ProcessImageStreamRequest, ProcessImageStreamResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method ProcessImageStream not implemented")
+}
+func (UnimplementedImageServiceServer) ProcessImageWithProgress(*ProcessImageRequest, grpc.ServerStreamingServer[ProcessImageProgress]) error {
+	return status.Errorf(codes.Unimplemented, "method ProcessImageWithProgress not implemented")
+}
 func (UnimplementedImageServiceServer) mustEmbedUnimplementedImageServiceServer() {}
 func (UnimplementedImageServiceServer) testEmbeddedByValue()                      {}
 
@@ -110,6 +164,24 @@ func _ImageService_ProcessImage_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ImageService_ProcessImageStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ImageServiceServer).ProcessImageStream(&grpc.GenericServerStream[ProcessImageStreamRequest, ProcessImageStreamResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ImageService_ProcessImageStreamServer = grpc.BidiStreamingServer[ProcessImageStreamRequest, ProcessImageStreamResponse]
+
+func _ImageService_ProcessImageWithProgress_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ProcessImageRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ImageServiceServer).ProcessImageWithProgress(m, &grpc.GenericServerStream[ProcessImageRequest, ProcessImageProgress]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type ImageService_ProcessImageWithProgressServer = grpc.ServerStreamingServer[ProcessImageProgress]
+
 // ImageService_ServiceDesc is the grpc.ServiceDesc for ImageService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -122,6 +194,18 @@ var ImageService_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _ImageService_ProcessImage_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ProcessImageStream",
+			Handler:       _ImageService_ProcessImageStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "ProcessImageWithProgress",
+			Handler:       _ImageService_ProcessImageWithProgress_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "internal/proto/image-service/image_service.proto",
 }
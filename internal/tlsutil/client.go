@@ -0,0 +1,69 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"stox-gateway/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// ClientConfig is a client TLS config whose CA bundle and client certificate
+// follow the files on disk
+type ClientConfig struct {
+	base *tls.Config
+	ca   *CAPoolReloader
+
+	// Reloaders keep the CA bundle and client certificate current and should
+	// be passed to Watch
+	Reloaders []Reloader
+}
+
+// NewClientConfig builds a client TLS config. An empty CA file uses the system
+// roots; a cert and key pair enables mTLS.
+func NewClientConfig(cfg config.ClientTLSConfig, logger *zap.Logger) (*ClientConfig, error) {
+	minVersion, err := ParseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+
+	c := &ClientConfig{
+		base: &tls.Config{
+			MinVersion: minVersion,
+			ServerName: cfg.ServerName,
+		},
+	}
+
+	if cfg.CAFile != "" {
+		ca, err := NewCAPoolReloader(cfg.CAFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		c.ca = ca
+		c.Reloaders = append(c.Reloaders, ca)
+	}
+
+	if cfg.CertFile != "" {
+		keyPair, err := NewKeyPairReloader(cfg.CertFile, cfg.KeyFile, logger)
+		if err != nil {
+			return nil, err
+		}
+		c.base.GetClientCertificate = keyPair.GetClientCertificate
+		c.Reloaders = append(c.Reloaders, keyPair)
+	}
+
+	return c, nil
+}
+
+// Config returns a config for a new connection, using the current CA bundle
+func (c *ClientConfig) Config() *tls.Config {
+	cfg := c.base.Clone()
+	if c.ca != nil {
+		cfg.RootCAs = c.ca.Pool()
+	}
+	return cfg
+}
@@ -0,0 +1,229 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// fileStamp identifies a version of a file on disk
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// stampFiles returns the current stamps of the given files
+func stampFiles(paths ...string) ([]fileStamp, error) {
+	stamps := make([]fileStamp, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, fileStamp{modTime: info.ModTime(), size: info.Size()})
+	}
+	return stamps, nil
+}
+
+// stampsEqual reports whether two sets of stamps describe the same files
+func stampsEqual(a, b []fileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
+
+// Reloader is implemented by anything Watch can keep up to date
+type Reloader interface {
+	// MaybeReload re-reads the underlying files if they changed on disk
+	MaybeReload()
+}
+
+// Watch polls the reloaders at the given interval until ctx is done
+func Watch(ctx context.Context, interval time.Duration, reloaders ...Reloader) {
+	if interval <= 0 || len(reloaders) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, reloader := range reloaders {
+				reloader.MaybeReload()
+			}
+		}
+	}
+}
+
+// KeyPairReloader serves a certificate and key pair, re-reading them when
+// either file changes so that rotated certificates apply without a restart
+type KeyPairReloader struct {
+	certFile string
+	keyFile  string
+	logger   *zap.Logger
+
+	mu     sync.RWMutex
+	cert   *tls.Certificate
+	stamps []fileStamp
+}
+
+// NewKeyPairReloader loads the key pair and returns a reloader for it
+func NewKeyPairReloader(certFile, keyFile string, logger *zap.Logger) (*KeyPairReloader, error) {
+	r := &KeyPairReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		logger:   logger,
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load reads the key pair from disk
+func (r *KeyPairReloader) load() error {
+	stamps, err := stampFiles(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat key pair: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load key pair %s: %w", r.certFile, err)
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.stamps = stamps
+	r.mu.Unlock()
+	return nil
+}
+
+// MaybeReload re-reads the key pair if either file changed. On failure the
+// previous certificate stays in use.
+func (r *KeyPairReloader) MaybeReload() {
+	stamps, err := stampFiles(r.certFile, r.keyFile)
+	if err != nil {
+		r.logger.Warn("Failed to check certificate files", zap.String("certFile", r.certFile), zap.Error(err))
+		return
+	}
+
+	r.mu.RLock()
+	unchanged := stampsEqual(stamps, r.stamps)
+	r.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	if err := r.load(); err != nil {
+		r.logger.Error("Failed to reload certificate, keeping the previous one", zap.Error(err))
+		return
+	}
+	r.logger.Info("Reloaded certificate", zap.String("certFile", r.certFile))
+}
+
+// Certificate returns the current certificate
+func (r *KeyPairReloader) Certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (r *KeyPairReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// GetClientCertificate implements tls.Config.GetClientCertificate
+func (r *KeyPairReloader) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return r.Certificate(), nil
+}
+
+// CAPoolReloader serves a CA bundle, re-reading it when the file changes
+type CAPoolReloader struct {
+	caFile string
+	logger *zap.Logger
+
+	mu     sync.RWMutex
+	pool   *x509.CertPool
+	stamps []fileStamp
+}
+
+// NewCAPoolReloader loads the CA bundle and returns a reloader for it
+func NewCAPoolReloader(caFile string, logger *zap.Logger) (*CAPoolReloader, error) {
+	r := &CAPoolReloader{
+		caFile: caFile,
+		logger: logger,
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// load reads the CA bundle from disk
+func (r *CAPoolReloader) load() error {
+	stamps, err := stampFiles(r.caFile)
+	if err != nil {
+		return fmt.Errorf("failed to stat CA bundle: %w", err)
+	}
+	data, err := os.ReadFile(r.caFile)
+	if err != nil {
+		return fmt.Errorf("failed to read CA bundle %s: %w", r.caFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no certificates found in CA bundle %s", r.caFile)
+	}
+
+	r.mu.Lock()
+	r.pool = pool
+	r.stamps = stamps
+	r.mu.Unlock()
+	return nil
+}
+
+// MaybeReload re-reads the CA bundle if it changed. On failure the previous
+// bundle stays in use.
+func (r *CAPoolReloader) MaybeReload() {
+	stamps, err := stampFiles(r.caFile)
+	if err != nil {
+		r.logger.Warn("Failed to check CA bundle", zap.String("caFile", r.caFile), zap.Error(err))
+		return
+	}
+
+	r.mu.RLock()
+	unchanged := stampsEqual(stamps, r.stamps)
+	r.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	if err := r.load(); err != nil {
+		r.logger.Error("Failed to reload CA bundle, keeping the previous one", zap.Error(err))
+		return
+	}
+	r.logger.Info("Reloaded CA bundle", zap.String("caFile", r.caFile))
+}
+
+// Pool returns the current CA pool
+func (r *CAPoolReloader) Pool() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pool
+}
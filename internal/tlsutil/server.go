@@ -0,0 +1,123 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"stox-gateway/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// ParseVersion maps "1.0" to "1.3" to a TLS version, defaulting to TLS 1.2
+func ParseVersion(version string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "tls") {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	}
+	return 0, fmt.Errorf("unsupported TLS version %q", version)
+}
+
+// ParseCipherSuites maps cipher suite names such as
+// "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256" to their IDs. Suites Go considers
+// insecure are rejected.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseClientAuth maps the config value to a tls.ClientAuthType
+func parseClientAuth(value string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require_any":
+		return tls.RequireAnyClientCert, nil
+	case "verify_if_given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return 0, fmt.Errorf("unsupported client_auth %q", value)
+}
+
+// NewServerConfig builds the gateway's TLS config. The returned reloaders keep
+// the certificate and client CA bundle current and should be passed to Watch.
+func NewServerConfig(cfg config.ServerTLSConfig, logger *zap.Logger) (*tls.Config, []Reloader, error) {
+	minVersion, err := ParseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	cipherSuites, err := ParseCipherSuites(cfg.CipherSuites)
+	if err != nil {
+		return nil, nil, err
+	}
+	clientAuth, err := parseClientAuth(cfg.ClientAuth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPair, err := NewKeyPairReloader(cfg.CertFile, cfg.KeyFile, logger)
+	if err != nil {
+		return nil, nil, err
+	}
+	reloaders := []Reloader{keyPair}
+
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		ClientAuth:     clientAuth,
+		GetCertificate: keyPair.GetCertificate,
+		// Set explicitly so per-handshake clones below still negotiate HTTP/2
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	verifiesClients := clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert
+	if verifiesClients && cfg.ClientCAFile == "" {
+		return nil, nil, fmt.Errorf("client_auth %q requires client_ca_file", cfg.ClientAuth)
+	}
+
+	if cfg.ClientCAFile != "" {
+		clientCAs, err := NewCAPoolReloader(cfg.ClientCAFile, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		reloaders = append(reloaders, clientCAs)
+
+		// Resolve the client CA pool per handshake so a rotated bundle
+		// applies to new connections straight away
+		tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			perConn := tlsConfig.Clone()
+			perConn.GetConfigForClient = nil
+			perConn.ClientCAs = clientCAs.Pool()
+			return perConn, nil
+		}
+	}
+
+	return tlsConfig, reloaders, nil
+}